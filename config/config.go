@@ -8,13 +8,24 @@ import (
 type (
 	// Config -.
 	Config struct {
-		App  `yaml:"app"`
-		HTTP `yaml:"http"`
-		Log  `yaml:"logger"`
-		PG   `yaml:"postgres"`
-		Auth `yaml:"auth"`
-		Jwt  `yaml:"jwt"`
-		Grpc `yaml:"grpc"`
+		App      `yaml:"app"`
+		HTTP     `yaml:"http"`
+		Log      `yaml:"logger"`
+		PG       `yaml:"postgres"`
+		Auth     `yaml:"auth"`
+		Jwt      `yaml:"jwt"`
+		Grpc     `yaml:"grpc"`
+		OAuth    `yaml:"oauth"`
+		WebAuthn `yaml:"webauthn"`
+		Mail     `yaml:"mail"`
+		Audit    `yaml:"audit"`
+		Outbox   `yaml:"outbox"`
+		Webhooks `yaml:"webhooks"`
+
+		PasswordPolicy `yaml:"password_policy" mapstructure:"password_policy"`
+		PasswordHash   `yaml:"password_hash" mapstructure:"password_hash"`
+		BruteForce     `yaml:"brute_force" mapstructure:"brute_force"`
+		RateLimit      `yaml:"rate_limit" mapstructure:"rate_limit"`
 	}
 
 	// App -.
@@ -26,6 +37,9 @@ type (
 	// HTTP -.
 	HTTP struct {
 		Port string `env-required:"true" yaml:"port" env:"HTTP_PORT"`
+		// TrustedProxies may set X-Forwarded-For, the client ip of requests
+		// from anywhere else is the remote address.
+		TrustedProxies []string `yaml:"trusted_proxies" mapstructure:"trusted_proxies"`
 	}
 
 	Grpc struct {
@@ -42,17 +56,155 @@ type (
 		PoolMax int    `env-required:"true" yaml:"pool_max" env:"PG_POOL_MAX"`
 		URL     string `env-required:"true" yaml:"url"      env:"PG_URL"`
 		Name    string `env-required:"true" yaml:"name"      env:"PG_NAME"`
+		DB      string `yaml:"db" env:"PG_DB"`
 	}
 
 	Auth struct {
-		Login    string `mapstructure:"login"`
-		Password string `mapstructure:"pass"`
+		Login       string   `mapstructure:"login"`
+		Password    string   `mapstructure:"pass"`
+		AdminEmails []string `mapstructure:"admin_emails"`
+		// RequireEmailVerification blocks the login until the email is verified.
+		RequireEmailVerification bool   `mapstructure:"require_email_verification"`
+		EmailVerificationTTL     int64  `mapstructure:"email_verification_ttl"`
+		EmailVerificationURL     string `mapstructure:"email_verification_url"`
+		PasswordResetTTL         int64  `mapstructure:"password_reset_ttl"`
+		PasswordResetURL         string `mapstructure:"password_reset_url"`
 	}
 
 	Jwt struct {
-		SecretKey       string `mapstructure:"secret_key"`
-		AccessTokenTTL  int64  `mapstructure:"access_token_ttl"`
-		RefreshTokenTTL int64  `mapstructure:"refresh_token_ttl"`
+		SecretKey           string `mapstructure:"secret_key"`
+		Algorithm           string `mapstructure:"algorithm"`
+		PrivateKeyPath      string `mapstructure:"private_key_path"`
+		KeyID               string `mapstructure:"key_id"`
+		KeysDir             string `mapstructure:"keys_dir"`
+		RotationGracePeriod int64  `mapstructure:"rotation_grace_period"`
+		AccessTokenTTL      int64  `mapstructure:"access_token_ttl"`
+		RefreshTokenTTL     int64  `mapstructure:"refresh_token_ttl"`
+	}
+
+	OAuth struct {
+		Issuer               string `mapstructure:"issuer"`
+		AuthorizationCodeTTL int64  `mapstructure:"authorization_code_ttl"`
+		LoginURL             string `mapstructure:"login_url"`
+	}
+
+	WebAuthn struct {
+		RPID          string   `mapstructure:"rp_id"`
+		RPDisplayName string   `mapstructure:"rp_display_name"`
+		RPOrigins     []string `mapstructure:"rp_origins"`
+	}
+
+	PasswordPolicy struct {
+		MinLength     int  `mapstructure:"min_length"`
+		MaxLength     int  `mapstructure:"max_length"`
+		RequireUpper  bool `mapstructure:"require_upper"`
+		RequireLower  bool `mapstructure:"require_lower"`
+		RequireDigit  bool `mapstructure:"require_digit"`
+		RequireSymbol bool `mapstructure:"require_symbol"`
+		// BlocklistPath is a file of common and breached passwords, one per line.
+		BlocklistPath string `mapstructure:"blocklist_path"`
+		// History is how many previous passwords may not be reused.
+		History int `mapstructure:"history"`
+	}
+
+	PasswordHash struct {
+		// Algorithm is bcrypt or argon2id. Hashes made with other settings
+		// are replaced on the next successful login.
+		Algorithm         string `mapstructure:"algorithm"`
+		BcryptCost        int    `mapstructure:"bcrypt_cost"`
+		Argon2Memory      uint32 `mapstructure:"argon2_memory"`
+		Argon2Iterations  uint32 `mapstructure:"argon2_iterations"`
+		Argon2Parallelism uint8  `mapstructure:"argon2_parallelism"`
+	}
+
+	// BruteForce limits password guessing. Durations are in seconds.
+	BruteForce struct {
+		// MaxFailures in a row lock the account for Lockout.
+		MaxFailures int   `mapstructure:"max_failures"`
+		Lockout     int64 `mapstructure:"lockout"`
+		// IPMaxFailures from one client ip are allowed before it is throttled.
+		IPMaxFailures int `mapstructure:"ip_max_failures"`
+		// Backoff is the delay after a failure, doubled with every further
+		// one up to MaxBackoff.
+		Backoff    int64 `mapstructure:"backoff"`
+		MaxBackoff int64 `mapstructure:"max_backoff"`
+		// Window is how long failures are remembered.
+		Window int64 `mapstructure:"window"`
+	}
+
+	// RateLimit limits requests per route and client. Every rule matching a
+	// request is applied.
+	RateLimit struct {
+		Enabled bool `mapstructure:"enabled"`
+		// Backend is memory or redis, Algorithm token_bucket or sliding_window.
+		Backend   string          `mapstructure:"backend"`
+		Algorithm string          `mapstructure:"algorithm"`
+		Rules     []RateLimitRule `mapstructure:"rules"`
+	}
+
+	// RateLimitRule allows Requests per Period seconds. Route is
+	// "METHOD /path" with the path as registered in the router, a bare path
+	// for every method, a gRPC full method name or * for everything. Key is
+	// ip or client, the latter falls back to the ip for anonymous requests.
+	RateLimitRule struct {
+		Route    string `mapstructure:"route"`
+		Key      string `mapstructure:"key"`
+		Requests int    `mapstructure:"requests"`
+		Period   int64  `mapstructure:"period"`
+		Burst    int    `mapstructure:"burst"`
+	}
+
+	Audit struct {
+		// Sinks receive every event: datastore, file and stdout.
+		Sinks []string `mapstructure:"sinks"`
+		// FilePath is the JSON lines file of the file sink.
+		FilePath string `mapstructure:"file_path"`
+	}
+
+	// Outbox relays the domain events written to the outbox table.
+	Outbox struct {
+		// Publisher is webhook or stdout, none keeps the events in the table.
+		Publisher string `mapstructure:"publisher"`
+		// WebhookURL receives every event as a JSON POST, WebhookTimeout is in seconds.
+		WebhookURL     string `mapstructure:"webhook_url"`
+		WebhookTimeout int64  `mapstructure:"webhook_timeout"`
+		// PollInterval is how often the table is scanned, in seconds.
+		PollInterval int64 `mapstructure:"poll_interval"`
+		BatchSize    int   `mapstructure:"batch_size"`
+		// Lease is how long a claimed event is hidden from other relays.
+		Lease int64 `mapstructure:"lease"`
+		// Backoff is the delay after a failed attempt, doubled with every
+		// further one up to MaxBackoff.
+		Backoff    int64 `mapstructure:"backoff"`
+		MaxBackoff int64 `mapstructure:"max_backoff"`
+		// Retention is how long published events are kept.
+		Retention int64 `mapstructure:"retention"`
+	}
+
+	// Webhooks delivers domain events to the endpoints registered through
+	// the admin api. Durations are in seconds.
+	Webhooks struct {
+		Timeout      int64 `mapstructure:"timeout"`
+		PollInterval int64 `mapstructure:"poll_interval"`
+		BatchSize    int   `mapstructure:"batch_size"`
+		Lease        int64 `mapstructure:"lease"`
+		// MaxAttempts failed in a row give the delivery up, it can still be
+		// replayed by hand.
+		MaxAttempts int   `mapstructure:"max_attempts"`
+		Backoff     int64 `mapstructure:"backoff"`
+		MaxBackoff  int64 `mapstructure:"max_backoff"`
+	}
+
+	Mail struct {
+		// Driver is smtp or file, the file driver writes messages to Dir
+		// instead of sending them.
+		Driver   string `mapstructure:"driver"`
+		From     string `mapstructure:"from"`
+		Host     string `mapstructure:"host"`
+		Port     int    `mapstructure:"port"`
+		Username string `mapstructure:"username"`
+		Password string `mapstructure:"password"`
+		Dir      string `mapstructure:"dir"`
 	}
 )
 
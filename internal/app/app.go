@@ -7,9 +7,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/madyar997/sso-jcode/internal/controller/grpc"
 	"github.com/madyar997/sso-jcode/internal/database"
+	"github.com/madyar997/sso-jcode/pkg/audit"
 	"github.com/madyar997/sso-jcode/pkg/cache"
+	"github.com/madyar997/sso-jcode/pkg/hasher"
 	"github.com/madyar997/sso-jcode/pkg/jaeger"
 	"github.com/madyar997/sso-jcode/pkg/logger"
+	"github.com/madyar997/sso-jcode/pkg/mailer"
+	"github.com/madyar997/sso-jcode/pkg/outbox"
+	"github.com/madyar997/sso-jcode/pkg/policy"
+	"github.com/madyar997/sso-jcode/pkg/ratelimit"
+	"github.com/madyar997/sso-jcode/pkg/signer"
+	"github.com/madyar997/sso-jcode/pkg/webhook"
 	"github.com/opentracing/opentracing-go"
 	"golang.org/x/sync/errgroup"
 	"log"
@@ -39,6 +47,7 @@ func Run(cfg *config.Config) {
 	ds, err := database.Connect(map[string]string{
 		"datastore": cfg.PG.Name,
 		"url":       cfg.PG.URL,
+		"db":        cfg.PG.DB,
 	})
 	if err != nil {
 		log.Printf("[ERROR] cannot connect to datastore: %v", err)
@@ -52,8 +61,67 @@ func Run(cfg *config.Config) {
 		return
 	}
 
+	keyRing, err := signer.New(cfg.Jwt)
+	if err != nil {
+		log.Printf("[ERROR] cannot create jwt signer: %v", err)
+		return
+	}
+
 	userCache := cache.NewUserCache(redisClient, cache.UserCacheTimeout)
-	userUseCase := usecase.NewUser(ds, cfg, l)
+	refreshTokenCache := cache.NewRefreshTokenCache(redisClient)
+	denylistCache := cache.NewDenylistCache(redisClient)
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		log.Printf("[ERROR] cannot create mailer: %v", err)
+		return
+	}
+
+	passwordPolicy, err := policy.NewPassword(cfg.PasswordPolicy)
+	if err != nil {
+		log.Printf("[ERROR] cannot load password policy: %v", err)
+		return
+	}
+
+	passwordHasher, err := hasher.New(cfg.PasswordHash)
+	if err != nil {
+		log.Printf("[ERROR] cannot create password hasher: %v", err)
+		return
+	}
+
+	auditor, err := audit.New(cfg.Audit, ds)
+	if err != nil {
+		log.Printf("[ERROR] cannot set up audit trail: %v", err)
+		return
+	}
+
+	userUseCase := usecase.NewUser(ds, cfg, l, keyRing, userCache, refreshTokenCache, denylistCache, cache.NewMFACache(redisClient),
+		cache.NewEmailVerificationCache(redisClient), cache.NewPasswordResetCache(redisClient), mail,
+		passwordPolicy, passwordHasher, cache.NewLoginAttemptsCache(redisClient), auditor)
+	clientUseCase := usecase.NewClient(ds, l)
+	auditUseCase := usecase.NewAudit(ds)
+	oauthUseCase := usecase.NewOAuth(userUseCase, clientUseCase, cache.NewAuthorizationCodeCache(redisClient), cfg)
+
+	passkeyUseCase, err := usecase.NewPasskey(userUseCase, cache.NewWebAuthnSessionCache(redisClient), cfg)
+	if err != nil {
+		log.Printf("[ERROR] cannot set up passkeys: %v", err)
+		return
+	}
+
+	rateLimit, err := ratelimit.New(cfg.RateLimit, redisClient)
+	if err != nil {
+		log.Printf("[ERROR] cannot set up rate limiting: %v", err)
+		return
+	}
+
+	webhookDispatcher := webhook.NewDispatcher(ds, l, cfg.Webhooks)
+	webhookUseCase := usecase.NewWebhook(ds, webhookDispatcher, l)
+
+	// user events go to the configured publisher and the registered webhooks
+	relay, err := outbox.New(cfg.Outbox, ds, l, webhookDispatcher)
+	if err != nil {
+		log.Printf("[ERROR] cannot set up outbox relay: %v", err)
+		return
+	}
 
 	go signalHandler(appCtxCancel)
 
@@ -61,7 +129,11 @@ func Run(cfg *config.Config) {
 
 	g.Go(func() error {
 		handler := gin.New()
-		v1.NewRouter(handler, l, userUseCase, userCache, cfg)
+		if err := handler.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+			return fmt.Errorf("HTTP trusted proxies: %v", err)
+		}
+		v1.NewRouter(handler, l, userUseCase, oauthUseCase, clientUseCase, passkeyUseCase, auditUseCase, webhookUseCase,
+			userCache, cfg, keyRing, rateLimit)
 		httpServer := httpserver.New(gCtx, cfg, handler)
 
 		err = httpServer.Run()
@@ -77,6 +149,7 @@ func Run(cfg *config.Config) {
 		grpcServer := grpc.NewGrpcServer(gCtx,
 			cfg.Grpc.Port,
 			userUseCase,
+			rateLimit,
 			cfg)
 
 		err = grpcServer.Run()
@@ -88,6 +161,14 @@ func Run(cfg *config.Config) {
 		return nil
 	})
 
+	g.Go(func() error {
+		return relay.Run(gCtx)
+	})
+
+	g.Go(func() error {
+		return webhookDispatcher.Run(gCtx)
+	})
+
 	// Ждем пока все горутины не будут завершены
 	if err = g.Wait(); err != nil {
 		log.Printf("[INFO] process terminated, %s", err)
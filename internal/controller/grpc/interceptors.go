@@ -0,0 +1,183 @@
+package grpc
+
+import (
+	"context"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/internal/usecase"
+	"github.com/madyar997/sso-jcode/pkg/ratelimit"
+	"github.com/madyar997/sso-jcode/pkg/tokenpb"
+	"github.com/madyar997/sso-jcode/pkg/userpb"
+	"github.com/madyar997/user-client/protobuf"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// methodScopes lists the scopes a caller needs for every exposed method.
+// Methods missing here are refused.
+var methodScopes = map[string][]string{
+	protobuf.User_GetUserByID_FullMethodName:   {entity.PermissionUsersRead},
+	tokenpb.Token_ValidateToken_FullMethodName: {entity.PermissionTokensIntrospect},
+
+	userpb.UserAdmin_UpdateUser_FullMethodName:     {entity.PermissionUsersWrite},
+	userpb.UserAdmin_PatchUser_FullMethodName:      {entity.PermissionUsersWrite},
+	userpb.UserAdmin_DeleteUser_FullMethodName:     {entity.PermissionUsersWrite},
+	userpb.UserAdmin_RestoreUser_FullMethodName:    {entity.PermissionUsersWrite},
+	userpb.UserAdmin_HardDeleteUser_FullMethodName: {entity.PermissionUsersWrite},
+}
+
+// authUnaryInterceptor requires a valid bearer token carrying the scopes of
+// the called method.
+func authUnaryInterceptor(u usecase.UserUseCase) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, u, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// authStreamInterceptor is the streaming counterpart of authUnaryInterceptor,
+// the token is checked once when the stream is opened.
+func authStreamInterceptor(u usecase.UserUseCase) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(ss.Context(), u, info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func authorize(ctx context.Context, u usecase.UserUseCase, fullMethod string) error {
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+
+	claims, err := u.VerifyAccessToken(ctx, token)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	required, ok := methodScopes[fullMethod]
+	if !ok {
+		return status.Errorf(codes.PermissionDenied, "method %s is not exposed", fullMethod)
+	}
+
+	granted := make(map[string]bool)
+	for _, scope := range usecase.GrantedScopes(claims) {
+		granted[scope] = true
+	}
+
+	for _, scope := range required {
+		if !granted[scope] {
+			return status.Errorf(codes.PermissionDenied, "scope %q required", scope)
+		}
+	}
+
+	return nil
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	for _, value := range md.Get("authorization") {
+		fields := strings.Fields(value)
+		if len(fields) == 2 && strings.EqualFold(fields[0], "Bearer") {
+			return fields[1], true
+		}
+	}
+
+	return "", false
+}
+
+// rateLimitUnaryInterceptor applies the rate limit policy before the call is
+// authorized, so floods of bad tokens are limited as well. Rejected calls get
+// ResourceExhausted with a RetryInfo detail and a retry-after header.
+func rateLimitUnaryInterceptor(p *ratelimit.Policy, u usecase.UserUseCase) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		retryAfter, err := rateLimit(ctx, p, u, info.FullMethod)
+		if err != nil {
+			_ = grpc.SetHeader(ctx, retryAfter)
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// rateLimitStreamInterceptor is the streaming counterpart of
+// rateLimitUnaryInterceptor, opening a stream counts as one request.
+func rateLimitStreamInterceptor(p *ratelimit.Policy, u usecase.UserUseCase) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		retryAfter, err := rateLimit(ss.Context(), p, u, info.FullMethod)
+		if err != nil {
+			_ = ss.SetHeader(retryAfter)
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func rateLimit(ctx context.Context, p *ratelimit.Policy, u usecase.UserUseCase, fullMethod string) (metadata.MD, error) {
+	client := func() string {
+		token, ok := bearerToken(ctx)
+		if !ok {
+			return ""
+		}
+
+		claims, err := u.VerifyAccessToken(ctx, token)
+		if err != nil {
+			return ""
+		}
+
+		return ratelimit.ClientKey(claims)
+	}
+
+	result, err := p.Check(ctx, "", fullMethod, peerIP(ctx), client)
+	if err != nil {
+		log.Printf("[ERROR] rate limit check failed: %s", err)
+	}
+	if result == nil || result.Allowed {
+		return nil, nil
+	}
+
+	seconds := int(math.Ceil(result.RetryAfter.Seconds()))
+
+	st, err := status.New(codes.ResourceExhausted, "rate limit exceeded").
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(result.RetryAfter)})
+	if err != nil {
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+
+	return metadata.Pairs("retry-after", strconv.Itoa(seconds)), st.Err()
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
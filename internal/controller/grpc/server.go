@@ -5,6 +5,9 @@ import (
 	"github.com/madyar997/sso-jcode/config"
 	v1 "github.com/madyar997/sso-jcode/internal/controller/grpc/v1"
 	"github.com/madyar997/sso-jcode/internal/usecase"
+	"github.com/madyar997/sso-jcode/pkg/ratelimit"
+	"github.com/madyar997/sso-jcode/pkg/tokenpb"
+	"github.com/madyar997/sso-jcode/pkg/userpb"
 	"github.com/madyar997/user-client/protobuf"
 	"google.golang.org/grpc"
 	"log"
@@ -20,12 +23,15 @@ type GrpcServer struct {
 	masterCtx       context.Context
 
 	userUseCase usecase.UserUseCase
+	rateLimit   *ratelimit.Policy
 }
 
-func NewGrpcServer(ctx context.Context, address string, userUseCase usecase.UserUseCase, cfg *config.Config) *GrpcServer {
+func NewGrpcServer(ctx context.Context, address string, userUseCase usecase.UserUseCase, rateLimit *ratelimit.Policy,
+	cfg *config.Config) *GrpcServer {
 	return &GrpcServer{
 		Address:         address,
 		userUseCase:     userUseCase,
+		rateLimit:       rateLimit,
 		cfg:             cfg,
 		idleConnsClosed: make(chan struct{}),
 		masterCtx:       ctx,
@@ -38,10 +44,21 @@ func (gs *GrpcServer) Run() error {
 		return err
 	}
 
-	gs.server = grpc.NewServer()
+	gs.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			rateLimitUnaryInterceptor(gs.rateLimit, gs.userUseCase),
+			authUnaryInterceptor(gs.userUseCase),
+		),
+		grpc.ChainStreamInterceptor(
+			rateLimitStreamInterceptor(gs.rateLimit, gs.userUseCase),
+			authStreamInterceptor(gs.userUseCase),
+		),
+	)
 
 	resource := v1.NewUserServiceResource(gs.userUseCase)
 	protobuf.RegisterUserServer(gs.server, resource)
+	tokenpb.RegisterTokenServer(gs.server, resource)
+	userpb.RegisterUserAdminServer(gs.server, resource)
 
 	go gs.GracefulShutdown(gs.server)
 
@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/internal/usecase"
+	"github.com/madyar997/sso-jcode/pkg/userpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (us *UserServiceResources) UpdateUser(ctx context.Context, req *userpb.UpdateUserRequest) (*userpb.AdminUser, error) {
+	user, err := us.userUseCase.UpdateUser(ctx, &entity.User{
+		Id:    int(req.Id),
+		Name:  req.Name,
+		Email: req.Email,
+		Age:   int(req.Age),
+	})
+	if err != nil {
+		return nil, accountError(err)
+	}
+
+	return adminUser(user), nil
+}
+
+func (us *UserServiceResources) PatchUser(ctx context.Context, req *userpb.PatchUserRequest) (*userpb.AdminUser, error) {
+	patch := &entity.UserPatch{
+		Name:          req.Name,
+		Email:         req.Email,
+		EmailVerified: req.EmailVerified,
+	}
+	if req.Age != nil {
+		age := int(*req.Age)
+		patch.Age = &age
+	}
+
+	user, err := us.userUseCase.PatchUser(ctx, int(req.Id), patch)
+	if err != nil {
+		return nil, accountError(err)
+	}
+
+	return adminUser(user), nil
+}
+
+func (us *UserServiceResources) DeleteUser(ctx context.Context, req *userpb.UserIdRequest) (*emptypb.Empty, error) {
+	if err := us.userUseCase.DeleteUser(ctx, int(req.Id)); err != nil {
+		return nil, accountError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (us *UserServiceResources) RestoreUser(ctx context.Context, req *userpb.UserIdRequest) (*userpb.AdminUser, error) {
+	user, err := us.userUseCase.RestoreUser(ctx, int(req.Id))
+	if err != nil {
+		return nil, accountError(err)
+	}
+
+	return adminUser(user), nil
+}
+
+func (us *UserServiceResources) HardDeleteUser(ctx context.Context, req *userpb.UserIdRequest) (*emptypb.Empty, error) {
+	if err := us.userUseCase.HardDeleteUser(ctx, int(req.Id)); err != nil {
+		return nil, accountError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// accountError maps the errors of the account methods onto grpc codes.
+func accountError(err error) error {
+	switch {
+	case errors.Is(err, drivers.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, usecase.ErrInvalidProfile):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, drivers.ErrUserAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, usecase.ErrUserNotDeleted):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return err
+	}
+}
+
+func adminUser(user *entity.User) *userpb.AdminUser {
+	return &userpb.AdminUser{
+		Id:            int32(user.Id),
+		Name:          user.Name,
+		Email:         user.Email,
+		Age:           int32(user.Age),
+		Roles:         user.Roles,
+		EmailVerified: user.EmailVerified,
+	}
+}
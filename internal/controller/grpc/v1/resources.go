@@ -3,11 +3,18 @@ package v1
 import (
 	"context"
 	"github.com/madyar997/sso-jcode/internal/usecase"
+	"github.com/madyar997/sso-jcode/pkg/tokenpb"
+	"github.com/madyar997/sso-jcode/pkg/userpb"
 	"github.com/madyar997/user-client/protobuf"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
 )
 
 type UserServiceResources struct {
 	protobuf.UnimplementedUserServer
+	tokenpb.UnimplementedTokenServer
+	userpb.UnimplementedUserAdminServer
 	userUseCase usecase.UserUseCase
 }
 
@@ -20,7 +27,7 @@ func NewUserServiceResource(userUseCase usecase.UserUseCase) *UserServiceResourc
 func (us *UserServiceResources) GetUserByID(ctx context.Context, req *protobuf.UserRequest) (*protobuf.UserResponse, error) {
 	user, err := us.userUseCase.GetUserByID(ctx, int(req.Id))
 	if err != nil {
-		return nil, err
+		return nil, accountError(err)
 	}
 
 	return &protobuf.UserResponse{
@@ -30,3 +37,26 @@ func (us *UserServiceResources) GetUserByID(ctx context.Context, req *protobuf.U
 		Age:   int32(user.Age),
 	}, nil
 }
+
+// ValidateToken introspects an access or refresh token for resource servers,
+// the gRPC counterpart of /oauth/introspect.
+func (us *UserServiceResources) ValidateToken(ctx context.Context, req *tokenpb.ValidateTokenRequest) (*tokenpb.ValidateTokenResponse, error) {
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	info, err := us.userUseCase.Introspect(ctx, req.Token, req.TokenTypeHint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenpb.ValidateTokenResponse{
+		Active:    info.Active,
+		Subject:   info.Sub,
+		Scopes:    strings.Fields(info.Scope),
+		ExpiresAt: info.Exp,
+		ClientId:  info.ClientID,
+		TokenType: info.TokenType,
+		UserId:    int32(info.UserID),
+	}, nil
+}
@@ -1,44 +1,45 @@
 package middleware
 
 import (
-	"fmt"
+	"context"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
-	"github.com/madyar997/sso-jcode/config"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/pkg/audit"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"github.com/madyar997/sso-jcode/pkg/ratelimit"
+	"go.uber.org/zap"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
-func JwtVerify(cfg *config.Config) gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		var tokenString string
-		tokenHeader := ctx.Request.Header.Get("Authorization")
-		tokenFields := strings.Fields(tokenHeader)
-		if len(tokenFields) == 2 && tokenFields[0] == "Bearer" {
-			tokenString = tokenFields[1]
-		} else {
-			ctx.AbortWithStatus(http.StatusForbidden)
-
-			return
-		}
+// BearerRealm opens the WWW-Authenticate challenge of RFC 6750.
+const BearerRealm = `Bearer realm="sso"`
 
-		claims := jwt.MapClaims{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-			}
+// TokenVerifier validates an access token, including its revocation state.
+type TokenVerifier interface {
+	VerifyAccessToken(ctx context.Context, token string) (jwt.MapClaims, error)
+}
 
-			return []byte(cfg.SecretKey), nil
-		})
-		if err != nil {
-			ctx.AbortWithStatus(http.StatusForbidden)
+// JwtVerify authenticates the request with the bearer access token. Following
+// RFC 6750 a missing or invalid token is answered with 401 and a
+// WWW-Authenticate challenge.
+func JwtVerify(v TokenVerifier) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tokenString, ok := bearerToken(ctx)
+		if !ok {
+			ctx.Header("WWW-Authenticate", BearerRealm)
+			ctx.AbortWithStatus(http.StatusUnauthorized)
 
 			return
 		}
 
-		if !token.Valid {
+		claims, err := v.VerifyAccessToken(ctx.Request.Context(), tokenString)
+		if err != nil {
+			ctx.Header("WWW-Authenticate", BearerRealm+`, error="invalid_token"`)
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 
 			return
@@ -50,7 +51,104 @@ func JwtVerify(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		ctx.Set("user_id", userID)
+		ctx.Set("claims", claims)
+
+		id, _ := userID.(float64)
+		email, _ := claims["email"].(string)
+		ctx.Request = ctx.Request.WithContext(audit.WithActor(ctx.Request.Context(), audit.Actor{ID: int(id), Email: email}))
+
+		ctx.Next()
+	}
+}
+
+// RequestOrigin attaches the client ip and user agent to the request context
+// for the audit trail.
+func RequestOrigin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		origin := entity.Origin{IP: ctx.ClientIP(), UserAgent: ctx.Request.UserAgent()}
+		ctx.Request = ctx.Request.WithContext(audit.WithOrigin(ctx.Request.Context(), origin))
+
+		ctx.Next()
+	}
+}
+
+// RequireRole lets the request through when the token carries one of the
+// roles, otherwise it is answered with 403. It must run after JwtVerify.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value, _ := ctx.Get("claims")
+		claims, ok := value.(jwt.MapClaims)
+		if !ok {
+			ctx.Header("WWW-Authenticate", BearerRealm)
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+
+			return
+		}
+
+		tokenRoles, _ := claims["roles"].([]interface{})
+		for _, tokenRole := range tokenRoles {
+			for _, role := range roles {
+				if tokenRole == role {
+					ctx.Next()
+
+					return
+				}
+			}
+		}
+
+		ctx.Header("WWW-Authenticate", BearerRealm+`, error="insufficient_scope"`)
+		ctx.AbortWithStatus(http.StatusForbidden)
+	}
+}
+
+// RateLimit applies the rate limit policy to every request. Requests over a
+// limit are answered with 429 and a Retry-After header. Rules keyed by
+// client count a valid bearer token against its OAuth client or its user.
+// Errors of the limiter let the request through.
+func RateLimit(p *ratelimit.Policy, v TokenVerifier, l *logger.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		client := func() string {
+			token, ok := bearerToken(ctx)
+			if !ok {
+				return ""
+			}
+
+			claims, err := v.VerifyAccessToken(ctx.Request.Context(), token)
+			if err != nil {
+				return ""
+			}
+
+			return ratelimit.ClientKey(claims)
+		}
+
+		result, err := p.Check(ctx.Request.Context(), ctx.Request.Method, ctx.FullPath(), ctx.ClientIP(), client)
+		if err != nil {
+			l.Error("rate limit check failed", zap.Error(err))
+		}
+		if result == nil {
+			ctx.Next()
+
+			return
+		}
+
+		ctx.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+
+			return
+		}
 
 		ctx.Next()
 	}
 }
+
+func bearerToken(ctx *gin.Context) (string, bool) {
+	fields := strings.Fields(ctx.Request.Header.Get("Authorization"))
+	if len(fields) == 2 && strings.EqualFold(fields[0], "Bearer") {
+		return fields[1], true
+	}
+	return "", false
+}
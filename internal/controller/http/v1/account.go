@@ -0,0 +1,200 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/internal/usecase"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// UpdateUser godoc
+// @Summary update user
+// @Description replaces the name, email and age of the user, a new email has to be verified again
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param        id       path  int                    true  "User ID"
+// @Param        request  body  dto.UpdateUserRequest  true  "Profile"
+// @Success      200  {object}  dto.UserInfo
+// @Failure      400  {object}  v1.response
+// @Failure      401
+// @Failure      403
+// @Failure      404  {object}  v1.response
+// @Failure      409  {object}  v1.response
+// @Failure      500  {object}  v1.response
+// @Router       /admin/user/{id} [put]
+func (ur *userRoutes) UpdateUser(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var request dto.UpdateUserRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	user, err := ur.u.UpdateUser(ctx, &entity.User{
+		Id:    id,
+		Name:  request.Name,
+		Email: request.Email,
+		Age:   request.Age,
+	})
+	if err != nil {
+		ur.failAccount(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, userInfo(user))
+}
+
+// PatchUser godoc
+// @Summary partially update user
+// @Description changes only the fields present in the body
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param        id       path  int                   true  "User ID"
+// @Param        request  body  dto.PatchUserRequest  true  "Changed fields"
+// @Success      200  {object}  dto.UserInfo
+// @Failure      400  {object}  v1.response
+// @Failure      401
+// @Failure      403
+// @Failure      404  {object}  v1.response
+// @Failure      409  {object}  v1.response
+// @Failure      500  {object}  v1.response
+// @Router       /admin/user/{id} [patch]
+func (ur *userRoutes) PatchUser(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var request dto.PatchUserRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	user, err := ur.u.PatchUser(ctx, id, &entity.UserPatch{
+		Name:          request.Name,
+		Email:         request.Email,
+		Age:           request.Age,
+		EmailVerified: request.EmailVerified,
+	})
+	if err != nil {
+		ur.failAccount(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, userInfo(user))
+}
+
+// DeleteUser godoc
+// @Summary delete user
+// @Description soft-deletes the user and ends all of their sessions, the account can be restored
+// @Tags users
+// @Param        id  path  int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  v1.response
+// @Failure      401
+// @Failure      403
+// @Failure      404  {object}  v1.response
+// @Failure      500  {object}  v1.response
+// @Router       /admin/user/{id} [delete]
+func (ur *userRoutes) DeleteUser(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id")
+	if !ok {
+		return
+	}
+
+	if err := ur.u.DeleteUser(ctx, id); err != nil {
+		ur.failAccount(ctx, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RestoreUser godoc
+// @Summary restore user
+// @Description brings a soft-deleted user back
+// @Tags users
+// @Produce json
+// @Param        id  path  int  true  "User ID"
+// @Success      200  {object}  dto.UserInfo
+// @Failure      400  {object}  v1.response
+// @Failure      401
+// @Failure      403
+// @Failure      404  {object}  v1.response
+// @Failure      409  {object}  v1.response
+// @Failure      500  {object}  v1.response
+// @Router       /admin/user/{id}/restore [post]
+func (ur *userRoutes) RestoreUser(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id")
+	if !ok {
+		return
+	}
+
+	user, err := ur.u.RestoreUser(ctx, id)
+	if err != nil {
+		ur.failAccount(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, userInfo(user))
+}
+
+// HardDeleteUser godoc
+// @Summary delete user permanently
+// @Description removes the user with their sessions and passkeys, soft-deleted or not
+// @Tags users
+// @Param        id  path  int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  v1.response
+// @Failure      401
+// @Failure      403
+// @Failure      404  {object}  v1.response
+// @Failure      500  {object}  v1.response
+// @Router       /admin/user/{id}/permanent [delete]
+func (ur *userRoutes) HardDeleteUser(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id")
+	if !ok {
+		return
+	}
+
+	if err := ur.u.HardDeleteUser(ctx, id); err != nil {
+		ur.failAccount(ctx, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (ur *userRoutes) failAccount(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, drivers.ErrUserNotFound):
+		errorResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, usecase.ErrInvalidProfile):
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, drivers.ErrUserAlreadyExists), errors.Is(err, usecase.ErrUserNotDeleted):
+		errorResponse(ctx, http.StatusConflict, err.Error())
+	default:
+		ur.l.Error("http - v1 - user - account", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "database problems")
+	}
+}
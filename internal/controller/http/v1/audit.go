@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/usecase"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type auditRoutes struct {
+	a usecase.AuditUseCase
+	l *logger.Logger
+}
+
+func newAuditRoutes(handler *gin.RouterGroup, a usecase.AuditUseCase, l *logger.Logger) {
+	r := &auditRoutes{a, l}
+
+	handler.GET("/audit", r.GetAuditEvents)
+}
+
+// GetAuditEvents godoc
+// @Summary query the audit trail
+// @Description returns a page of audit events, newest first
+// @Tags admin
+// @Produce json
+// @Param        type       query  string  false  "Event type, like login.failed"
+// @Param        actor_id   query  int     false  "User who acted"
+// @Param        target_id  query  int     false  "User acted on"
+// @Param        ip         query  string  false  "Client ip"
+// @Param        from       query  string  false  "RFC 3339 time, inclusive"
+// @Param        to         query  string  false  "RFC 3339 time, exclusive"
+// @Param        limit      query  int     false  "Page size, 50 by default and at most 500"
+// @Param        offset     query  int     false  "Events to skip"
+// @Success      200  {object}  dto.AuditEventsResponse
+// @Failure      400  {object}  v1.response
+// @Failure      401
+// @Failure      403
+// @Router       /admin/audit [get]
+func (ar *auditRoutes) GetAuditEvents(ctx *gin.Context) {
+	var request dto.AuditQueryRequest
+
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	events, total, err := ar.a.AuditEvents(ctx, &request)
+	if err != nil {
+		ar.l.Error("http - v1 - audit", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "database problems")
+
+		return
+	}
+
+	response := dto.AuditEventsResponse{
+		Events: make([]dto.AuditEvent, 0, len(events)),
+		Total:  total,
+		Limit:  request.Limit,
+		Offset: request.Offset,
+	}
+	for _, event := range events {
+		response.Events = append(response.Events, dto.AuditEvent{
+			ID:          event.Id,
+			Type:        event.Type,
+			ActorID:     event.ActorID,
+			ActorEmail:  event.ActorEmail,
+			TargetID:    event.TargetID,
+			TargetEmail: event.TargetEmail,
+			IP:          event.IP,
+			UserAgent:   event.UserAgent,
+			TraceID:     event.TraceID,
+			Details:     event.Details,
+			CreatedAt:   event.CreatedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,201 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/internal/usecase"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type clientRoutes struct {
+	c usecase.ClientUseCase
+	l *logger.Logger
+}
+
+func newClientRoutes(handler *gin.RouterGroup, c usecase.ClientUseCase, l *logger.Logger) {
+	r := &clientRoutes{c, l}
+
+	adminHandler := handler.Group("/clients")
+	{
+		adminHandler.GET("", r.GetClients)
+		adminHandler.GET("/:client_id", r.GetClient)
+		adminHandler.POST("", r.CreateClient)
+		adminHandler.PUT("/:client_id", r.UpdateClient)
+		adminHandler.DELETE("/:client_id", r.DeleteClient)
+		adminHandler.POST("/:client_id/secret", r.RotateSecret)
+	}
+}
+
+// GetClients godoc
+// @Summary list oauth clients
+// @Tags clients
+// @Produce json
+// @Success      200  {array}   dto.ClientInfo
+// @Failure      401
+// @Failure      403
+// @Failure      500  {object}  v1.response
+// @Router       /admin/clients [get]
+func (cr *clientRoutes) GetClients(ctx *gin.Context) {
+	clients, err := cr.c.Clients(ctx)
+	if err != nil {
+		cr.l.Error("http - v1 - clients - list", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "database problems")
+
+		return
+	}
+
+	response := make([]dto.ClientInfo, 0, len(clients))
+	for _, client := range clients {
+		response = append(response, clientInfo(client))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetClient godoc
+// @Summary get oauth client
+// @Tags clients
+// @Produce json
+// @Param        client_id  path  string  true  "Client ID"
+// @Success      200  {object}  dto.ClientInfo
+// @Failure      404  {object}  v1.response
+// @Router       /admin/clients/{client_id} [get]
+func (cr *clientRoutes) GetClient(ctx *gin.Context) {
+	client, err := cr.c.GetClient(ctx, ctx.Param("client_id"))
+	if err != nil {
+		cr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, clientInfo(client))
+}
+
+// CreateClient godoc
+// @Summary register oauth client
+// @Description the client secret is only returned once
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Param        request  body  dto.CreateClientRequest  true  "Client metadata"
+// @Success      201  {object}  dto.ClientCredentials
+// @Failure      400  {object}  v1.response
+// @Failure      409  {object}  v1.response
+// @Router       /admin/clients [post]
+func (cr *clientRoutes) CreateClient(ctx *gin.Context) {
+	var request dto.CreateClientRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	client, secret, err := cr.c.CreateClient(ctx, &request)
+	if err != nil {
+		cr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, dto.ClientCredentials{ClientInfo: clientInfo(client), ClientSecret: secret})
+}
+
+// UpdateClient godoc
+// @Summary update oauth client
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Param        client_id  path  string                   true  "Client ID"
+// @Param        request    body  dto.UpdateClientRequest  true  "Client metadata"
+// @Success      200  {object}  dto.ClientInfo
+// @Failure      400  {object}  v1.response
+// @Failure      404  {object}  v1.response
+// @Router       /admin/clients/{client_id} [put]
+func (cr *clientRoutes) UpdateClient(ctx *gin.Context) {
+	var request dto.UpdateClientRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	client, err := cr.c.UpdateClient(ctx, ctx.Param("client_id"), &request)
+	if err != nil {
+		cr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, clientInfo(client))
+}
+
+// DeleteClient godoc
+// @Summary delete oauth client
+// @Tags clients
+// @Param        client_id  path  string  true  "Client ID"
+// @Success      204
+// @Failure      404  {object}  v1.response
+// @Router       /admin/clients/{client_id} [delete]
+func (cr *clientRoutes) DeleteClient(ctx *gin.Context) {
+	if err := cr.c.DeleteClient(ctx, ctx.Param("client_id")); err != nil {
+		cr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RotateSecret godoc
+// @Summary rotate client secret
+// @Description issues a new secret for a confidential client, the old one stops working immediately
+// @Tags clients
+// @Produce json
+// @Param        client_id  path  string  true  "Client ID"
+// @Success      200  {object}  dto.ClientCredentials
+// @Failure      400  {object}  v1.response
+// @Failure      404  {object}  v1.response
+// @Router       /admin/clients/{client_id}/secret [post]
+func (cr *clientRoutes) RotateSecret(ctx *gin.Context) {
+	client, secret, err := cr.c.RotateSecret(ctx, ctx.Param("client_id"))
+	if err != nil {
+		cr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.ClientCredentials{ClientInfo: clientInfo(client), ClientSecret: secret})
+}
+
+func (cr *clientRoutes) fail(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, drivers.ErrClientNotFound):
+		errorResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, drivers.ErrClientAlreadyExists):
+		errorResponse(ctx, http.StatusConflict, err.Error())
+	case errors.Is(err, usecase.ErrInvalidClientMetadata):
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+	default:
+		cr.l.Error("http - v1 - clients", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "database problems")
+	}
+}
+
+func clientInfo(client *entity.Client) dto.ClientInfo {
+	return dto.ClientInfo{
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		Public:       client.Public(),
+		RedirectURIs: client.RedirectURIs,
+		Scopes:       client.Scopes,
+		GrantTypes:   client.GrantTypes,
+		CreatedAt:    client.CreatedAt,
+	}
+}
@@ -0,0 +1,38 @@
+package dto
+
+import "time"
+
+// AuditQueryRequest filters the audit trail. From and To are RFC 3339 times,
+// From is inclusive and To exclusive.
+type AuditQueryRequest struct {
+	Type     string    `form:"type"`
+	ActorID  int       `form:"actor_id"`
+	TargetID int       `form:"target_id"`
+	IP       string    `form:"ip"`
+	From     time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit    int       `form:"limit" binding:"gte=0"`
+	Offset   int       `form:"offset" binding:"gte=0"`
+}
+
+type AuditEvent struct {
+	ID          int               `json:"id"`
+	Type        string            `json:"type"`
+	ActorID     int               `json:"actor_id,omitempty"`
+	ActorEmail  string            `json:"actor_email,omitempty"`
+	TargetID    int               `json:"target_id,omitempty"`
+	TargetEmail string            `json:"target_email,omitempty"`
+	IP          string            `json:"ip,omitempty"`
+	UserAgent   string            `json:"user_agent,omitempty"`
+	TraceID     string            `json:"trace_id,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+type AuditEventsResponse struct {
+	Events []AuditEvent `json:"events"`
+	// Total counts every event matching the filters, not only this page.
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+}
@@ -0,0 +1,36 @@
+package dto
+
+import "time"
+
+type CreateClientRequest struct {
+	ClientID     string   `json:"client_id"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	GrantTypes   []string `json:"grant_types"`
+	// Public clients get no secret and must use PKCE.
+	Public bool `json:"public"`
+}
+
+type UpdateClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	GrantTypes   []string `json:"grant_types"`
+}
+
+type ClientInfo struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	Public       bool      `json:"public"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	GrantTypes   []string  `json:"grant_types"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ClientCredentials carries the client secret, it is only shown once.
+type ClientCredentials struct {
+	ClientInfo
+	ClientSecret string `json:"client_secret,omitempty"`
+}
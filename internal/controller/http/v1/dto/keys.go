@@ -0,0 +1,6 @@
+package dto
+
+type RotateKeyResponse struct {
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+}
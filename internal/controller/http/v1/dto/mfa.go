@@ -0,0 +1,18 @@
+package dto
+
+// TOTPEnrollment is returned when an authenticator is provisioned. QRCode is
+// a PNG data url encoding OTPAuthURI.
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RecoveryCodesResponse is shown once, only hashes of the codes are stored.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
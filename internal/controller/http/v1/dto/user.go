@@ -1,25 +1,135 @@
 package dto
 
+import "time"
+
 type RegisterRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+// CreateUserRequest is the account an admin creates. Everything else about
+// the user, such as verification and MFA state, is set by the server.
+type CreateUserRequest struct {
+	Name     string   `json:"name"`
+	Email    string   `json:"email" binding:"required"`
+	Age      int      `json:"age"`
+	Password string   `json:"password" binding:"required"`
+	Roles    []string `json:"roles"`
+}
+
+// VerifyEmailRequest carries the token from the verification link.
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+// LoginResponse carries the token pair, or only the MFA challenge token when
+// MFARequired is set and the second factor still has to be verified.
 type LoginResponse struct {
 	Name         string `json:"name"`
 	Email        string `json:"email"`
-	AccessToken  string `json:"access_token"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	MFAToken     string `json:"mfa_token,omitempty"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	// Code is a TOTP code or one of the recovery codes.
+	Code string `json:"code" binding:"required"`
+}
+
+type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+type LogoutRequest struct {
+	AllSessions bool `json:"all_sessions"`
+}
+
 type UserInfo struct {
-	Id    int    `json:"id"`
+	Id            int       `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	Age           int       `json:"age"`
+	Roles         []string  `json:"roles"`
+	Permissions   []string  `json:"permissions,omitempty"`
+	MFAEnabled    bool      `json:"mfa_enabled"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// UserQueryRequest filters, sorts and pages the user list. Email and Name
+// match substrings, the age bounds and CreatedFrom are inclusive, CreatedTo
+// is exclusive. Cursor continues after the last user of a previous page and
+// replaces Offset.
+type UserQueryRequest struct {
+	Email       string    `form:"email"`
+	Name        string    `form:"name"`
+	MinAge      *int      `form:"min_age" binding:"omitempty,gte=0"`
+	MaxAge      *int      `form:"max_age" binding:"omitempty,gte=0"`
+	CreatedFrom time.Time `form:"created_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedTo   time.Time `form:"created_to" time_format:"2006-01-02T15:04:05Z07:00"`
+	SortBy      string    `form:"sort_by"`
+	SortOrder   string    `form:"sort_order"`
+	Limit       int       `form:"limit" binding:"gte=0"`
+	Offset      int       `form:"offset" binding:"gte=0"`
+	Cursor      string    `form:"cursor"`
+}
+
+type UsersResponse struct {
+	Users []UserInfo `json:"users"`
+	// Total counts every user matching the filters, not only this page.
+	Total     int64  `json:"total"`
+	Limit     int    `json:"limit"`
+	Offset    int    `json:"offset"`
+	SortBy    string `json:"sort_by"`
+	SortOrder string `json:"sort_order"`
+	// NextCursor and Next, the link to the following page, are empty on the
+	// last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+}
+
+// UpdateUserRequest replaces the profile of a user.
+type UpdateUserRequest struct {
 	Name  string `json:"name"`
-	Email string `json:"email"`
+	Email string `json:"email" binding:"required"`
 	Age   int    `json:"age"`
 }
+
+// PatchUserRequest changes only the fields present in the body.
+type PatchUserRequest struct {
+	Name          *string `json:"name"`
+	Email         *string `json:"email"`
+	Age           *int    `json:"age"`
+	EmailVerified *bool   `json:"email_verified"`
+}
+
+type UpdateRolesRequest struct {
+	Roles       []string `json:"roles" binding:"required"`
+	Permissions []string `json:"permissions"`
+}
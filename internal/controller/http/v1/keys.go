@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"github.com/madyar997/sso-jcode/pkg/signer"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type keyRoutes struct {
+	kr *signer.KeyRing
+	l  *logger.Logger
+}
+
+func newKeyRoutes(handler *gin.RouterGroup, kr *signer.KeyRing, l *logger.Logger) {
+	r := &keyRoutes{kr, l}
+
+	adminHandler := handler.Group("/keys")
+	{
+		adminHandler.POST("/rotate", r.Rotate)
+	}
+}
+
+// Rotate godoc
+// @Summary rotate signing key
+// @Description generates a new signing key, previous one keeps verifying tokens for the grace period
+// @Tags keys
+// @Produce json
+// @Success      200  {object}  dto.RotateKeyResponse
+// @Failure      500  {object}  v1.response
+// @Router       /admin/keys/rotate [post]
+func (kr *keyRoutes) Rotate(ctx *gin.Context) {
+	key, err := kr.kr.Rotate()
+	if err != nil {
+		kr.l.Error("http - v1 - keys - rotate", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "could not rotate signing key")
+
+		return
+	}
+
+	kr.l.Info("signing key rotated", zap.String("kid", key.ID))
+
+	ctx.JSON(http.StatusOK, dto.RotateKeyResponse{
+		KeyID:     key.ID,
+		Algorithm: key.Method.Alg(),
+	})
+}
@@ -0,0 +1,180 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/usecase"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// LoginMFA godoc
+// @Summary complete login with the second factor
+// @Description exchanges the mfa_token returned by login and a TOTP or recovery code for tokens
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param        request  body  dto.LoginMFARequest  true  "MFA token and code"
+// @Success      200  {object}  dto.LoginResponse
+// @Failure      400  {object}  v1.response
+// @Failure      401  {object}  v1.response
+// @Failure      500  {object}  v1.response
+// @Router       /user/login/mfa [post]
+func (ur *userRoutes) LoginMFA(ctx *gin.Context) {
+	var request dto.LoginMFARequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	token, err := ur.u.LoginMFA(ctx, request.MFAToken, request.Code, requestOrigin(ctx))
+	if errors.Is(err, usecase.ErrInvalidMFAToken) || errors.Is(err, usecase.ErrInvalidMFACode) {
+		errorResponse(ctx, http.StatusUnauthorized, err.Error())
+
+		return
+	}
+	if err != nil {
+		ur.l.Error("could not complete mfa login ", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "could not login")
+
+		return
+	}
+
+	ctx.SetCookie("access_token", token.AccessToken, 3600, "/", "localhost", false, true)
+	ctx.SetCookie("refresh_token", token.RefreshToken, 3600, "/", "localhost", false, true)
+
+	ctx.JSON(http.StatusOK, token)
+}
+
+// EnrollTOTP godoc
+// @Summary enroll an authenticator app
+// @Description provisions a TOTP secret, MFA is enabled after the first code is confirmed
+// @Tags mfa
+// @Produce json
+// @Success      200  {object}  dto.TOTPEnrollment
+// @Failure      401
+// @Failure      409  {object}  v1.response
+// @Failure      500  {object}  v1.response
+// @Router       /user/mfa/totp [post]
+func (ur *userRoutes) EnrollTOTP(ctx *gin.Context) {
+	userID, ok := claimsUserID(ctx)
+	if !ok {
+		return
+	}
+
+	enrollment, err := ur.u.EnrollTOTP(ctx, userID)
+	if err != nil {
+		ur.mfaFail(ctx, err)
+
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP godoc
+// @Summary confirm authenticator enrollment
+// @Description enables MFA and returns the recovery codes, they are shown only once
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param        request  body  dto.MFACodeRequest  true  "Current TOTP code"
+// @Success      200  {object}  dto.RecoveryCodesResponse
+// @Failure      400  {object}  v1.response
+// @Failure      401  {object}  v1.response
+// @Failure      409  {object}  v1.response
+// @Router       /user/mfa/totp/confirm [post]
+func (ur *userRoutes) ConfirmTOTP(ctx *gin.Context) {
+	userID, ok := claimsUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var request dto.MFACodeRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	codes, err := ur.u.ConfirmTOTP(ctx, userID, request.Code)
+	if err != nil {
+		ur.mfaFail(ctx, err)
+
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableMFA godoc
+// @Summary disable multi-factor authentication
+// @Tags mfa
+// @Accept json
+// @Param        request  body  dto.MFACodeRequest  true  "TOTP or recovery code"
+// @Success      204
+// @Failure      400  {object}  v1.response
+// @Failure      401  {object}  v1.response
+// @Failure      409  {object}  v1.response
+// @Router       /user/mfa [delete]
+func (ur *userRoutes) DisableMFA(ctx *gin.Context) {
+	userID, ok := claimsUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var request dto.MFACodeRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if err := ur.u.DisableMFA(ctx, userID, request.Code); err != nil {
+		ur.mfaFail(ctx, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (ur *userRoutes) mfaFail(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidMFACode):
+		errorResponse(ctx, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, usecase.ErrMFAAlreadyEnabled),
+		errors.Is(err, usecase.ErrMFANotEnrolled),
+		errors.Is(err, usecase.ErrMFANotEnabled):
+		errorResponse(ctx, http.StatusConflict, err.Error())
+	case errors.Is(err, drivers.ErrUserNotFound):
+		errorResponse(ctx, http.StatusNotFound, err.Error())
+	default:
+		ur.l.Error("http - v1 - mfa", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "database problems")
+	}
+}
+
+// claimsUserID returns the user of the verified access token. Tokens without
+// a user, like client credential tokens, are refused with 403.
+func claimsUserID(ctx *gin.Context) (int, bool) {
+	claims := ctx.MustGet("claims").(jwt.MapClaims)
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok || userID == 0 {
+		errorResponse(ctx, http.StatusForbidden, "token does not belong to a user")
+
+		return 0, false
+	}
+
+	return int(userID), true
+}
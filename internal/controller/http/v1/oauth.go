@@ -0,0 +1,359 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/madyar997/sso-jcode/config"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/usecase"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type oauthRoutes struct {
+	u   usecase.UserUseCase
+	o   usecase.OAuthUseCase
+	l   *logger.Logger
+	cfg *config.Config
+}
+
+// oauthError is the error body defined by RFC 6749 section 5.2.
+type oauthError struct {
+	Error            string `json:"error" example:"invalid_request"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func oauthErrorResponse(c *gin.Context, code int, err, description string) {
+	c.AbortWithStatusJSON(code, oauthError{err, description})
+}
+
+func newOAuthRoutes(handler *gin.Engine, u usecase.UserUseCase, o usecase.OAuthUseCase, l *logger.Logger, cfg *config.Config) {
+	r := &oauthRoutes{u, o, l, cfg}
+
+	oauthHandler := handler.Group("/oauth")
+	{
+		oauthHandler.GET("/authorize", r.Authorize)
+		oauthHandler.POST("/authorize", r.Authorize)
+		oauthHandler.POST("/token", r.Token)
+		oauthHandler.POST("/revoke", r.Revoke)
+		oauthHandler.POST("/introspect", r.Introspect)
+	}
+}
+
+// Authorize godoc
+// @Summary authorization endpoint
+// @Description authorization code grant with PKCE (S256). GET uses the SSO session from the access_token
+// @Description cookie or bearer header, POST additionally accepts email and password form fields.
+// @Tags oauth
+// @Param        response_type          query  string  true   "code"
+// @Param        client_id              query  string  true   "Client ID"
+// @Param        redirect_uri           query  string  false  "Registered redirect URI"
+// @Param        scope                  query  string  false  "Requested scope"
+// @Param        state                  query  string  false  "Opaque client state"
+// @Param        code_challenge         query  string  true   "PKCE code challenge"
+// @Param        code_challenge_method  query  string  true   "S256"
+// @Success      302
+// @Failure      400  {object}  v1.oauthError
+// @Failure      401  {object}  v1.response
+// @Router       /oauth/authorize [get]
+func (or *oauthRoutes) Authorize(ctx *gin.Context) {
+	var authorizeRequest dto.AuthorizeRequest
+
+	if err := ctx.ShouldBind(&authorizeRequest); err != nil {
+		oauthErrorResponse(ctx, http.StatusBadRequest, "invalid_request", err.Error())
+
+		return
+	}
+
+	redirectURI, err := or.o.ValidateClient(ctx, authorizeRequest.ClientID, authorizeRequest.RedirectURI)
+	if err != nil {
+		or.oauthFail(ctx, err)
+
+		return
+	}
+
+	userID, authTime, ok := or.authenticate(ctx)
+	if ctx.IsAborted() {
+		return
+	}
+	if !ok {
+		if or.cfg.LoginURL != "" && ctx.Request.Method == http.MethodGet {
+			ctx.Redirect(http.StatusFound, or.cfg.LoginURL+"?return_to="+url.QueryEscape(ctx.Request.URL.String()))
+
+			return
+		}
+
+		redirectWithParams(ctx, redirectURI, url.Values{
+			"error":             {"login_required"},
+			"error_description": {"user is not authenticated"},
+			"state":             {authorizeRequest.State},
+		})
+
+		return
+	}
+
+	code, err := or.o.Authorize(ctx, &authorizeRequest, userID, authTime, requestOrigin(ctx))
+	if err != nil {
+		var oauthErr *usecase.OAuthError
+		if !errors.As(err, &oauthErr) {
+			or.l.Error("http - v1 - oauth - authorize", zap.Error(err))
+			oauthErr = &usecase.OAuthError{Code: "server_error"}
+		}
+
+		redirectWithParams(ctx, redirectURI, url.Values{
+			"error":             {oauthErr.Code},
+			"error_description": {oauthErr.Description},
+			"state":             {authorizeRequest.State},
+		})
+
+		return
+	}
+
+	redirectWithParams(ctx, redirectURI, url.Values{
+		"code":  {code},
+		"state": {authorizeRequest.State},
+	})
+}
+
+// authenticate resolves the user and the time they authenticated from the
+// posted credentials or the SSO session. Wrong credentials abort the request with 401.
+func (or *oauthRoutes) authenticate(ctx *gin.Context) (int, time.Time, bool) {
+	if email := ctx.PostForm("email"); email != "" {
+		user, err := or.u.Authenticate(ctx, email, ctx.PostForm("password"), ctx.ClientIP())
+		if loginThrottled(ctx, err) {
+			return 0, time.Time{}, false
+		}
+		if errors.Is(err, usecase.ErrEmailNotVerified) {
+			errorResponse(ctx, http.StatusForbidden, err.Error())
+
+			return 0, time.Time{}, false
+		}
+		if err != nil {
+			errorResponse(ctx, http.StatusUnauthorized, usecase.ErrInvalidCredentials.Error())
+
+			return 0, time.Time{}, false
+		}
+
+		// the second factor is only checked by the login flow, which sets the SSO session
+		if user.MFAEnabled {
+			errorResponse(ctx, http.StatusUnauthorized, usecase.ErrMFARequired.Error())
+
+			return 0, time.Time{}, false
+		}
+
+		return user.Id, time.Now(), true
+	}
+
+	token, err := ctx.Cookie("access_token")
+	if fields := strings.Fields(ctx.GetHeader("Authorization")); len(fields) == 2 && fields[0] == "Bearer" {
+		token, err = fields[1], nil
+	}
+	if err != nil || token == "" {
+		return 0, time.Time{}, false
+	}
+
+	claims, err := or.u.VerifyAccessToken(ctx, token)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+
+	// only a first-party session may approve a grant, a token issued to an
+	// OAuth client must not be replayed to mint codes for other clients
+	_, delegated := claims["client_id"]
+	_, scoped := claims["scope"]
+	if delegated || scoped {
+		return 0, time.Time{}, false
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	authTime, _ := claims["auth_time"].(float64)
+
+	return int(userID), time.Unix(int64(authTime), 0), ok
+}
+
+// Token godoc
+// @Summary token endpoint
+// @Description exchanges an authorization code (with its PKCE code_verifier), a refresh token or client credentials for tokens.
+// @Description Confidential clients authenticate with HTTP Basic or client_secret in the body
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param        grant_type     formData  string  true   "authorization_code, refresh_token or client_credentials"
+// @Param        code           formData  string  false  "Authorization code"
+// @Param        redirect_uri   formData  string  false  "Redirect URI used in the authorization request"
+// @Param        client_id      formData  string  false  "Client ID"
+// @Param        client_secret  formData  string  false  "Client secret"
+// @Param        code_verifier  formData  string  false  "PKCE code verifier"
+// @Param        refresh_token  formData  string  false  "Refresh token"
+// @Param        scope          formData  string  false  "Requested scope for client_credentials"
+// @Success      200  {object}  dto.TokenResponse
+// @Failure      400  {object}  v1.oauthError
+// @Failure      401  {object}  v1.oauthError
+// @Router       /oauth/token [post]
+func (or *oauthRoutes) Token(ctx *gin.Context) {
+	var tokenRequest dto.TokenRequest
+
+	if err := ctx.ShouldBind(&tokenRequest); err != nil {
+		oauthErrorResponse(ctx, http.StatusBadRequest, "invalid_request", err.Error())
+
+		return
+	}
+
+	if !basicClientCredentials(ctx, &tokenRequest.ClientID, &tokenRequest.ClientSecret) {
+		oauthErrorResponse(ctx, http.StatusUnauthorized, "invalid_client", "malformed client credentials")
+
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	token, err := or.o.Token(ctx, &tokenRequest)
+	if err != nil {
+		or.oauthFail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, token)
+}
+
+// Introspect godoc
+// @Summary introspect token
+// @Description RFC 7662 token introspection for access and refresh tokens, the caller authenticates as a confidential client
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param        token            formData  string  true   "Token to introspect"
+// @Param        token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Param        client_id        formData  string  false  "Client ID"
+// @Param        client_secret    formData  string  false  "Client secret"
+// @Success      200  {object}  dto.IntrospectionResponse
+// @Failure      400  {object}  v1.oauthError
+// @Failure      401  {object}  v1.oauthError
+// @Router       /oauth/introspect [post]
+func (or *oauthRoutes) Introspect(ctx *gin.Context) {
+	var introspectionRequest dto.IntrospectionRequest
+
+	if err := ctx.ShouldBind(&introspectionRequest); err != nil {
+		oauthErrorResponse(ctx, http.StatusBadRequest, "invalid_request", err.Error())
+
+		return
+	}
+
+	if !basicClientCredentials(ctx, &introspectionRequest.ClientID, &introspectionRequest.ClientSecret) {
+		oauthErrorResponse(ctx, http.StatusUnauthorized, "invalid_client", "malformed client credentials")
+
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+
+	response, err := or.o.Introspect(ctx, &introspectionRequest)
+	if err != nil {
+		or.oauthFail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// basicClientCredentials takes the client credentials from HTTP Basic
+// authentication when present. RFC 6749 section 2.3.1: they are form-encoded
+// before being base64 encoded. It returns false for malformed credentials.
+func basicClientCredentials(ctx *gin.Context, clientID, clientSecret *string) bool {
+	id, secret, ok := ctx.Request.BasicAuth()
+	if !ok {
+		return true
+	}
+
+	var err error
+	if *clientID, err = url.QueryUnescape(id); err != nil {
+		return false
+	}
+	if *clientSecret, err = url.QueryUnescape(secret); err != nil {
+		return false
+	}
+
+	return true
+}
+
+// oauthFail writes an OAuth error body, invalid_client is reported with 401.
+func (or *oauthRoutes) oauthFail(ctx *gin.Context, err error) {
+	var oauthErr *usecase.OAuthError
+	if !errors.As(err, &oauthErr) {
+		or.l.Error("http - v1 - oauth", zap.Error(err))
+		oauthErrorResponse(ctx, http.StatusInternalServerError, "server_error", "")
+
+		return
+	}
+
+	code := http.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		code = http.StatusUnauthorized
+	}
+
+	oauthErrorResponse(ctx, code, oauthErr.Code, oauthErr.Description)
+}
+
+func redirectWithParams(ctx *gin.Context, redirectURI string, params url.Values) {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		oauthErrorResponse(ctx, http.StatusBadRequest, "invalid_request", "redirect_uri is malformed")
+
+		return
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			query.Set(key, values[0])
+		}
+	}
+	target.RawQuery = query.Encode()
+
+	ctx.Redirect(http.StatusFound, target.String())
+}
+
+// Revoke godoc
+// @Summary revoke token
+// @Description RFC 7009 token revocation, accepts access and refresh tokens
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param        token            formData  string  true   "Token to revoke"
+// @Param        token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Param        client_id        formData  string  false  "Client ID"
+// @Param        client_secret    formData  string  false  "Client secret"
+// @Success      200
+// @Failure      400  {object}  v1.oauthError
+// @Failure      401  {object}  v1.oauthError
+// @Router       /oauth/revoke [post]
+func (or *oauthRoutes) Revoke(ctx *gin.Context) {
+	var revocationRequest dto.RevocationRequest
+
+	if err := ctx.ShouldBind(&revocationRequest); err != nil {
+		oauthErrorResponse(ctx, http.StatusBadRequest, "invalid_request", err.Error())
+
+		return
+	}
+
+	if !basicClientCredentials(ctx, &revocationRequest.ClientID, &revocationRequest.ClientSecret) {
+		oauthErrorResponse(ctx, http.StatusUnauthorized, "invalid_client", "malformed client credentials")
+
+		return
+	}
+
+	if err := or.o.Revoke(ctx, &revocationRequest); err != nil {
+		or.oauthFail(ctx, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/madyar997/sso-jcode/internal/controller/http/middleware"
+	"github.com/madyar997/sso-jcode/internal/usecase"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type oidcRoutes struct {
+	o usecase.OAuthUseCase
+	l *logger.Logger
+}
+
+func newOIDCRoutes(handler *gin.Engine, u usecase.UserUseCase, o usecase.OAuthUseCase, l *logger.Logger) {
+	r := &oidcRoutes{o, l}
+
+	handler.GET("/.well-known/openid-configuration", r.Discovery)
+	handler.GET("/userinfo", middleware.JwtVerify(u), r.UserInfo)
+	handler.POST("/userinfo", middleware.JwtVerify(u), r.UserInfo)
+}
+
+// Discovery godoc
+// @Summary OpenID provider metadata
+// @Tags oidc
+// @Produce json
+// @Success      200  {object}  dto.OpenIDConfiguration
+// @Router       /.well-known/openid-configuration [get]
+func (or *oidcRoutes) Discovery(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, or.o.Discovery())
+}
+
+// UserInfo godoc
+// @Summary OpenID Connect userinfo
+// @Description returns the claims of the access token owner allowed by its scope
+// @Tags oidc
+// @Produce json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401
+// @Failure      403
+// @Failure      500  {object}  v1.response
+// @Router       /userinfo [get]
+func (or *oidcRoutes) UserInfo(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(jwt.MapClaims)
+
+	info, err := or.o.UserInfo(ctx, claims)
+	if errors.Is(err, usecase.ErrInvalidAccessToken) {
+		ctx.Header("WWW-Authenticate", middleware.BearerRealm+`, error="invalid_token"`)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+
+		return
+	}
+	if errors.Is(err, usecase.ErrInsufficientScope) {
+		ctx.Header("WWW-Authenticate", middleware.BearerRealm+`, error="insufficient_scope", scope="openid"`)
+		ctx.AbortWithStatus(http.StatusForbidden)
+
+		return
+	}
+	if err != nil {
+		or.l.Error("http - v1 - oidc - userinfo", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "database problems")
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, info)
+}
@@ -0,0 +1,230 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/madyar997/sso-jcode/internal/controller/http/middleware"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/usecase"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type passkeyRoutes struct {
+	p usecase.PasskeyUseCase
+	l *logger.Logger
+}
+
+func newPasskeyRoutes(handler *gin.RouterGroup, u usecase.UserUseCase, p usecase.PasskeyUseCase, l *logger.Logger) {
+	r := &passkeyRoutes{p, l}
+
+	loginHandler := handler.Group("/user/login/passkey")
+	{
+		loginHandler.POST("/begin", r.BeginLogin)
+		loginHandler.POST("/finish", r.FinishLogin)
+	}
+
+	passkeyHandler := handler.Group("/user/passkeys", middleware.JwtVerify(u))
+	{
+		passkeyHandler.GET("", r.GetPasskeys)
+		passkeyHandler.DELETE("/:credential_id", r.DeletePasskey)
+		passkeyHandler.POST("/register/begin", r.BeginRegistration)
+		passkeyHandler.POST("/register/finish", r.FinishRegistration)
+	}
+}
+
+// BeginLogin godoc
+// @Summary start passkey login
+// @Description returns the options for navigator.credentials.get()
+// @Tags passkeys
+// @Produce json
+// @Success      200  {object}  dto.PasskeyCeremony
+// @Failure      500  {object}  v1.response
+// @Router       /user/login/passkey/begin [post]
+func (pr *passkeyRoutes) BeginLogin(ctx *gin.Context) {
+	ceremony, err := pr.p.BeginLogin(ctx)
+	if err != nil {
+		pr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ceremony)
+}
+
+// FinishLogin godoc
+// @Summary finish passkey login
+// @Description verifies the assertion and issues tokens like the password login
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Param        request  body  dto.PasskeyFinishRequest  true  "Ceremony and credential"
+// @Success      200  {object}  dto.LoginResponse
+// @Failure      400  {object}  v1.response
+// @Failure      401  {object}  v1.response
+// @Router       /user/login/passkey/finish [post]
+func (pr *passkeyRoutes) FinishLogin(ctx *gin.Context) {
+	var request dto.PasskeyFinishRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	token, err := pr.p.FinishLogin(ctx, &request, requestOrigin(ctx))
+	if err != nil {
+		pr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.SetCookie("access_token", token.AccessToken, 3600, "/", "localhost", false, true)
+	ctx.SetCookie("refresh_token", token.RefreshToken, 3600, "/", "localhost", false, true)
+
+	ctx.JSON(http.StatusOK, token)
+}
+
+// GetPasskeys godoc
+// @Summary list passkeys
+// @Tags passkeys
+// @Produce json
+// @Success      200  {array}   dto.PasskeyInfo
+// @Failure      401
+// @Router       /user/passkeys [get]
+func (pr *passkeyRoutes) GetPasskeys(ctx *gin.Context) {
+	userID, ok := claimsUserID(ctx)
+	if !ok {
+		return
+	}
+
+	passkeys, err := pr.p.Passkeys(ctx, userID)
+	if err != nil {
+		pr.fail(ctx, err)
+
+		return
+	}
+
+	response := make([]dto.PasskeyInfo, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		response = append(response, dto.PasskeyInfo{
+			CredentialID:   passkey.CredentialID,
+			Name:           passkey.Name,
+			Transports:     passkey.Transports,
+			BackupEligible: passkey.BackupEligible,
+			BackupState:    passkey.BackupState,
+			CreatedAt:      passkey.CreatedAt,
+			LastUsedAt:     passkey.LastUsedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// DeletePasskey godoc
+// @Summary delete passkey
+// @Tags passkeys
+// @Param        credential_id  path  string  true  "Credential ID"
+// @Success      204
+// @Failure      401
+// @Failure      404  {object}  v1.response
+// @Router       /user/passkeys/{credential_id} [delete]
+func (pr *passkeyRoutes) DeletePasskey(ctx *gin.Context) {
+	userID, ok := claimsUserID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := pr.p.DeletePasskey(ctx, userID, ctx.Param("credential_id")); err != nil {
+		pr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// BeginRegistration godoc
+// @Summary start passkey registration
+// @Description returns the options for navigator.credentials.create()
+// @Tags passkeys
+// @Produce json
+// @Success      200  {object}  dto.PasskeyCeremony
+// @Failure      401
+// @Router       /user/passkeys/register/begin [post]
+func (pr *passkeyRoutes) BeginRegistration(ctx *gin.Context) {
+	userID, ok := claimsUserID(ctx)
+	if !ok {
+		return
+	}
+
+	ceremony, err := pr.p.BeginRegistration(ctx, userID)
+	if err != nil {
+		pr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ceremony)
+}
+
+// FinishRegistration godoc
+// @Summary finish passkey registration
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Param        request  body  dto.PasskeyFinishRequest  true  "Ceremony, name and credential"
+// @Success      201  {object}  dto.PasskeyInfo
+// @Failure      400  {object}  v1.response
+// @Failure      401
+// @Failure      409  {object}  v1.response
+// @Router       /user/passkeys/register/finish [post]
+func (pr *passkeyRoutes) FinishRegistration(ctx *gin.Context) {
+	userID, ok := claimsUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var request dto.PasskeyFinishRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	passkey, err := pr.p.FinishRegistration(ctx, userID, &request)
+	if err != nil {
+		pr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, dto.PasskeyInfo{
+		CredentialID:   passkey.CredentialID,
+		Name:           passkey.Name,
+		Transports:     passkey.Transports,
+		BackupEligible: passkey.BackupEligible,
+		BackupState:    passkey.BackupState,
+		CreatedAt:      passkey.CreatedAt,
+	})
+}
+
+func (pr *passkeyRoutes) fail(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidCeremony),
+		errors.Is(err, usecase.ErrPasskeyVerification):
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, usecase.ErrPasskeyCloned):
+		errorResponse(ctx, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, drivers.ErrPasskeyNotFound):
+		errorResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, drivers.ErrPasskeyAlreadyExists):
+		errorResponse(ctx, http.StatusConflict, err.Error())
+	default:
+		pr.l.Error("http - v1 - passkeys", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "database problems")
+	}
+}
@@ -0,0 +1,127 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/usecase"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// ForgotPassword godoc
+// @Summary request a password reset
+// @Description mails a one-time reset link, the answer is the same whether the account exists or not
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param        request  body  dto.ForgotPasswordRequest  true  "Email"
+// @Success      202
+// @Failure      400  {object}  v1.response
+// @Router       /user/password/forgot [post]
+func (ur *userRoutes) ForgotPassword(ctx *gin.Context) {
+	var request dto.ForgotPasswordRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if err := ur.u.ForgotPassword(ctx, request.Email); err != nil {
+		ur.l.Error("could not send password reset email ", zap.Error(err))
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "if the account exists, a reset link was sent"})
+}
+
+// ResetPassword godoc
+// @Summary reset password
+// @Description sets a new password with the token from the reset link and ends every session of the user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param        request  body  dto.ResetPasswordRequest  true  "Token and new password"
+// @Success      200
+// @Failure      400  {object}  v1.response
+// @Failure      500  {object}  v1.response
+// @Router       /user/password/reset [post]
+func (ur *userRoutes) ResetPassword(ctx *gin.Context) {
+	var request dto.ResetPasswordRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	err := ur.u.ResetPassword(ctx, request.Token, request.Password)
+	if errors.Is(err, usecase.ErrInvalidResetToken) || errors.Is(err, usecase.ErrWeakPassword) ||
+		errors.Is(err, usecase.ErrPasswordReused) {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+	if err != nil {
+		ur.l.Error("could not reset password ", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "could not reset password")
+
+		return
+	}
+
+	ctx.SetCookie("access_token", "", -1, "/", "localhost", false, true)
+	ctx.SetCookie("refresh_token", "", -1, "/", "localhost", false, true)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "password successfully reset"})
+}
+
+// ChangePassword godoc
+// @Summary change password
+// @Description replaces the password after checking the current one and ends every session of the user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param        request  body  dto.ChangePasswordRequest  true  "Current and new password"
+// @Success      200
+// @Failure      400  {object}  v1.response
+// @Failure      401
+// @Failure      403  {object}  v1.response
+// @Failure      500  {object}  v1.response
+// @Router       /user/password/change [post]
+func (ur *userRoutes) ChangePassword(ctx *gin.Context) {
+	userID, ok := claimsUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var request dto.ChangePasswordRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	err := ur.u.ChangePassword(ctx, userID, request.CurrentPassword, request.NewPassword)
+	switch {
+	case err == nil:
+	case errors.Is(err, usecase.ErrWrongPassword):
+		errorResponse(ctx, http.StatusForbidden, err.Error())
+
+		return
+	case errors.Is(err, usecase.ErrWeakPassword), errors.Is(err, usecase.ErrPasswordReused):
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	default:
+		ur.l.Error("could not change password ", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "could not change password")
+
+		return
+	}
+
+	ctx.SetCookie("access_token", "", -1, "/", "localhost", false, true)
+	ctx.SetCookie("refresh_token", "", -1, "/", "localhost", false, true)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "password successfully changed"})
+}
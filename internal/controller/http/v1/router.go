@@ -5,7 +5,11 @@ import (
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
 	"github.com/madyar997/sso-jcode/config"
+	"github.com/madyar997/sso-jcode/internal/controller/http/middleware"
+	"github.com/madyar997/sso-jcode/internal/entity"
 	"github.com/madyar997/sso-jcode/pkg/cache"
+	"github.com/madyar997/sso-jcode/pkg/ratelimit"
+	"github.com/madyar997/sso-jcode/pkg/signer"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	_ "github.com/santosh/gingo/docs"
 	swaggerFiles "github.com/swaggo/files"
@@ -24,9 +28,16 @@ import (
 // @version     1.0
 // @host        localhost:8080
 // @BasePath    /api/v1
-func NewRouter(handler *gin.Engine, l *logger.Logger, u usecase.UserUseCase, uc cache.User, cfg *config.Config) {
+func NewRouter(handler *gin.Engine, l *logger.Logger, u usecase.UserUseCase, o usecase.OAuthUseCase,
+	c usecase.ClientUseCase, p usecase.PasskeyUseCase, a usecase.AuditUseCase, w usecase.WebhookUseCase, uc cache.User,
+	cfg *config.Config, kr *signer.KeyRing, rl *ratelimit.Policy) {
 	// Options
-	handler.Use(gin.Recovery())
+	// use cases get the gin context, values of the request context must stay visible
+	handler.ContextWithFallback = true
+	handler.Use(gin.Recovery(), middleware.RequestOrigin())
+	if rl != nil {
+		handler.Use(middleware.RateLimit(rl, u, l))
+	}
 
 	pprof.Register(handler)
 	handler.Static("/assets", "./docs")
@@ -43,9 +54,23 @@ func NewRouter(handler *gin.Engine, l *logger.Logger, u usecase.UserUseCase, uc
 	// Prometheus metrics
 	handler.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	// Public keys for verifying issued tokens
+	handler.GET("/.well-known/jwks.json", func(c *gin.Context) { c.JSON(http.StatusOK, kr.JWKS()) })
+
+	// OAuth 2.0
+	newOAuthRoutes(handler, u, o, l, cfg)
+	newOIDCRoutes(handler, u, o, l)
+
 	// Routers
 	h := handler.Group("/api/v1")
 	{
-		newUserRoutes(h, u, l, uc, cfg)
+		admin := h.Group("/admin", middleware.JwtVerify(u), middleware.RequireRole(entity.RoleAdmin))
+
+		newUserRoutes(h, admin, u, l, uc, cfg)
+		newPasskeyRoutes(h, u, p, l)
+		newKeyRoutes(admin, kr, l)
+		newClientRoutes(admin, c, l)
+		newAuditRoutes(admin, a, l)
+		newWebhookRoutes(admin, w, l)
 	}
 }
@@ -0,0 +1,177 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+// GetSessions godoc
+// @Summary list sessions
+// @Description lists the devices the user is logged in on, the most recently used first
+// @Tags sessions
+// @Produce json
+// @Success      200  {array}   dto.SessionInfo
+// @Failure      401
+// @Router       /user/sessions [get]
+func (ur *userRoutes) GetSessions(ctx *gin.Context) {
+	userID, ok := claimsUserID(ctx)
+	if !ok {
+		return
+	}
+
+	sessions, err := ur.u.Sessions(ctx, userID)
+	if err != nil {
+		ur.failSession(ctx, err)
+
+		return
+	}
+
+	sid, _ := ctx.MustGet("claims").(jwt.MapClaims)["sid"].(string)
+
+	ctx.JSON(http.StatusOK, sessionInfos(sessions, sid))
+}
+
+// EndSession godoc
+// @Summary end session
+// @Description logs the user out on one device, the refresh and access tokens of the session stop working
+// @Tags sessions
+// @Param        id  path  string  true  "Session ID"
+// @Success      204
+// @Failure      401
+// @Failure      404  {object}  v1.response
+// @Router       /user/sessions/{id} [delete]
+func (ur *userRoutes) EndSession(ctx *gin.Context) {
+	userID, ok := claimsUserID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ur.u.EndSession(ctx, userID, ctx.Param("id")); err != nil {
+		ur.failSession(ctx, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetUserSessions godoc
+// @Summary list sessions of a user
+// @Tags admin
+// @Produce json
+// @Param        id  path  int  true  "User ID"
+// @Success      200  {array}   dto.SessionInfo
+// @Failure      400  {object}  v1.response
+// @Failure      404  {object}  v1.response
+// @Router       /admin/user/{id}/sessions [get]
+func (ur *userRoutes) GetUserSessions(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		errorResponse(ctx, http.StatusBadRequest, "id is incorrect")
+
+		return
+	}
+
+	if _, err = ur.u.GetUserByID(ctx, id); err != nil {
+		ur.failSession(ctx, err)
+
+		return
+	}
+
+	sessions, err := ur.u.Sessions(ctx, id)
+	if err != nil {
+		ur.failSession(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sessionInfos(sessions, ""))
+}
+
+// EndUserSession godoc
+// @Summary force logout of one session
+// @Tags admin
+// @Param        id          path  int     true  "User ID"
+// @Param        session_id  path  string  true  "Session ID"
+// @Success      204
+// @Failure      400  {object}  v1.response
+// @Failure      404  {object}  v1.response
+// @Router       /admin/user/{id}/sessions/{session_id} [delete]
+func (ur *userRoutes) EndUserSession(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		errorResponse(ctx, http.StatusBadRequest, "id is incorrect")
+
+		return
+	}
+
+	if err = ur.u.EndSession(ctx, id, ctx.Param("session_id")); err != nil {
+		ur.failSession(ctx, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// EndUserSessions godoc
+// @Summary force logout of every session
+// @Description ends every session of the user, tokens issued until now stop working
+// @Tags admin
+// @Param        id  path  int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  v1.response
+// @Failure      404  {object}  v1.response
+// @Router       /admin/user/{id}/sessions [delete]
+func (ur *userRoutes) EndUserSessions(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		errorResponse(ctx, http.StatusBadRequest, "id is incorrect")
+
+		return
+	}
+
+	if err = ur.u.EndSessions(ctx, id); err != nil {
+		ur.failSession(ctx, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (ur *userRoutes) failSession(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, drivers.ErrSessionNotFound), errors.Is(err, drivers.ErrUserNotFound):
+		errorResponse(ctx, http.StatusNotFound, err.Error())
+	default:
+		ur.l.Error("http - v1 - sessions", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "database problems")
+	}
+}
+
+func sessionInfos(sessions []*entity.Session, currentID string) []dto.SessionInfo {
+	response := make([]dto.SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, dto.SessionInfo{
+			ID:         session.ID,
+			ClientID:   session.ClientID,
+			Device:     session.Device,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentID,
+		})
+	}
+
+	return response
+}
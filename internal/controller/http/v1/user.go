@@ -1,11 +1,13 @@
 package v1
 
 import (
-	"fmt"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 	"github.com/madyar997/sso-jcode/config"
+	"github.com/madyar997/sso-jcode/internal/controller/http/middleware"
 	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
 	"github.com/madyar997/sso-jcode/internal/entity"
 	"github.com/madyar997/sso-jcode/internal/usecase"
 	"github.com/madyar997/sso-jcode/pkg/cache"
@@ -13,9 +15,9 @@ import (
 	"github.com/madyar997/sso-jcode/pkg/logger"
 	"github.com/opentracing/opentracing-go"
 	"go.uber.org/zap"
+	"math"
 	"net/http"
 	"strconv"
-	"time"
 )
 
 type userRoutes struct {
@@ -25,27 +27,93 @@ type userRoutes struct {
 	cfg       *config.Config
 }
 
-func newUserRoutes(handler *gin.RouterGroup, u usecase.UserUseCase, l *logger.Logger, uc cache.User, cfg *config.Config) {
+func newUserRoutes(handler, admin *gin.RouterGroup, u usecase.UserUseCase, l *logger.Logger, uc cache.User, cfg *config.Config) {
 	r := &userRoutes{u, l, uc, cfg}
 
-	adminHandler := handler.Group("/admin/user")
+	adminHandler := admin.Group("/user")
 	{
 		adminHandler.GET("/:id", r.GetUserByID)
 		adminHandler.GET("/all", r.GetUsers)
 		adminHandler.POST("/", r.CreateUser)
 		adminHandler.GET("/", r.GetUserByEmail)
+		adminHandler.PUT("/:id", r.UpdateUser)
+		adminHandler.PATCH("/:id", r.PatchUser)
+		adminHandler.DELETE("/:id", r.DeleteUser)
+		adminHandler.POST("/:id/restore", r.RestoreUser)
+		adminHandler.DELETE("/:id/permanent", r.HardDeleteUser)
+		adminHandler.PUT("/:id/roles", r.SetRoles)
+		adminHandler.POST("/:id/unlock", r.UnlockUser)
+		adminHandler.GET("/:id/sessions", r.GetUserSessions)
+		adminHandler.DELETE("/:id/sessions", r.EndUserSessions)
+		adminHandler.DELETE("/:id/sessions/:session_id", r.EndUserSession)
 	}
 
 	userHandler := handler.Group("/user")
 	{
 		userHandler.POST("/register", r.Register)
+		userHandler.GET("/verify-email", r.VerifyEmail)
+		userHandler.POST("/verify-email", r.VerifyEmail)
+		userHandler.POST("/verify-email/resend", r.ResendVerification)
+		userHandler.POST("/password/forgot", r.ForgotPassword)
+		userHandler.POST("/password/reset", r.ResetPassword)
+		userHandler.POST("/password/change", middleware.JwtVerify(u), r.ChangePassword)
 		userHandler.POST("/login", r.Login)
+		userHandler.POST("/login/mfa", r.LoginMFA)
 		userHandler.POST("/refresh", r.Refresh)
+		userHandler.POST("/logout", middleware.JwtVerify(u), r.Logout)
+	}
+
+	sessionHandler := userHandler.Group("/sessions", middleware.JwtVerify(u))
+	{
+		sessionHandler.GET("", r.GetSessions)
+		sessionHandler.DELETE("/:id", r.EndSession)
+	}
+
+	mfaHandler := userHandler.Group("/mfa", middleware.JwtVerify(u))
+	{
+		mfaHandler.POST("/totp", r.EnrollTOTP)
+		mfaHandler.POST("/totp/confirm", r.ConfirmTOTP)
+		mfaHandler.DELETE("", r.DisableMFA)
 	}
 }
 
+// GetUsers godoc
+// @Summary list users
+// @Description returns a page of the users, filtered and sorted; use next_cursor or the next link for the following page
+// @Tags users
+// @Produce json
+// @Param        email         query  string  false  "Part of the email, case-insensitive"
+// @Param        name          query  string  false  "Part of the name, case-insensitive"
+// @Param        min_age       query  int     false  "Minimal age, inclusive"
+// @Param        max_age       query  int     false  "Maximal age, inclusive"
+// @Param        created_from  query  string  false  "RFC 3339 time, inclusive"
+// @Param        created_to    query  string  false  "RFC 3339 time, exclusive"
+// @Param        sort_by       query  string  false  "id, name, email, age or created_at; id by default"
+// @Param        sort_order    query  string  false  "asc or desc; asc by default"
+// @Param        limit         query  int     false  "Page size, 50 by default and at most 500"
+// @Param        offset        query  int     false  "Users to skip"
+// @Param        cursor        query  string  false  "next_cursor of the previous page, replaces offset"
+// @Success      200  {object}  dto.UsersResponse
+// @Failure      400  {object}  v1.response
+// @Failure      401
+// @Failure      403
+// @Failure      500  {object}  v1.response
+// @Router       /admin/user/all [get]
 func (ur *userRoutes) GetUsers(ctx *gin.Context) {
-	users, err := ur.u.Users(ctx)
+	var request dto.UserQueryRequest
+
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	page, err := ur.u.Users(ctx, &request)
+	if errors.Is(err, usecase.ErrInvalidUserQuery) {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
 	if err != nil {
 		ur.l.Logger.Error("error getting the user", zap.Error(err))
 		errorResponse(ctx, http.StatusInternalServerError, "database problems")
@@ -53,20 +121,47 @@ func (ur *userRoutes) GetUsers(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, users)
+	response := dto.UsersResponse{
+		Users:      make([]dto.UserInfo, 0, len(page.Users)),
+		Total:      page.Total,
+		Limit:      request.Limit,
+		Offset:     request.Offset,
+		SortBy:     request.SortBy,
+		SortOrder:  request.SortOrder,
+		NextCursor: page.NextCursor,
+	}
+	for _, user := range page.Users {
+		response.Users = append(response.Users, userInfo(user))
+	}
+
+	if page.NextCursor != "" {
+		next := *ctx.Request.URL
+		query := next.Query()
+		query.Del("offset")
+		query.Set("cursor", page.NextCursor)
+		next.RawQuery = query.Encode()
+		response.Next = next.RequestURI()
+	}
+
+	ctx.JSON(http.StatusOK, response)
 }
 
 func (ur *userRoutes) CreateUser(ctx *gin.Context) {
-	var user *entity.User
+	var createRequest dto.CreateUserRequest
 
-	err := ctx.ShouldBindJSON(&user)
+	err := ctx.ShouldBindJSON(&createRequest)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, err)
 
 		return
 	}
 
-	insertedID, err := ur.u.CreateUser(ctx, user)
+	insertedID, err := ur.u.CreateUser(ctx, &createRequest)
+	if errors.Is(err, usecase.ErrUnknownRole) || errors.Is(err, usecase.ErrWeakPassword) {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, err)
 
@@ -87,6 +182,16 @@ func (ur *userRoutes) Register(ctx *gin.Context) {
 	}
 
 	err = ur.u.Register(ctx, registerRequest.Email, registerRequest.Password)
+	if errors.Is(err, usecase.ErrWeakPassword) {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+	if errors.Is(err, drivers.ErrUserAlreadyExists) {
+		errorResponse(ctx, http.StatusConflict, err.Error())
+
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, err)
 
@@ -96,6 +201,78 @@ func (ur *userRoutes) Register(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "user successfully registered"})
 }
 
+// VerifyEmail godoc
+// @Summary verify email
+// @Description confirms the email address with the token from the verification link, sent as ?token= or in the body
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param        token    query  string                  false  "Verification token"
+// @Param        request  body   dto.VerifyEmailRequest  false  "Verification token"
+// @Success      200
+// @Failure      400  {object}  v1.response
+// @Failure      500  {object}  v1.response
+// @Router       /user/verify-email [post]
+func (ur *userRoutes) VerifyEmail(ctx *gin.Context) {
+	var request dto.VerifyEmailRequest
+
+	request.Token = ctx.Query("token")
+	if request.Token == "" && ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			errorResponse(ctx, http.StatusBadRequest, "invalid request body")
+
+			return
+		}
+	}
+
+	if request.Token == "" {
+		errorResponse(ctx, http.StatusBadRequest, "token is required")
+
+		return
+	}
+
+	err := ur.u.VerifyEmail(ctx, request.Token)
+	if errors.Is(err, usecase.ErrInvalidVerification) {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+	if err != nil {
+		ur.l.Error("could not verify email ", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "could not verify email")
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "email successfully verified"})
+}
+
+// ResendVerification godoc
+// @Summary resend verification email
+// @Description mails a new verification link, the answer is the same whether the account exists or not
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param        request  body  dto.ResendVerificationRequest  true  "Email"
+// @Success      202
+// @Failure      400  {object}  v1.response
+// @Router       /user/verify-email/resend [post]
+func (ur *userRoutes) ResendVerification(ctx *gin.Context) {
+	var request dto.ResendVerificationRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if err := ur.u.ResendVerification(ctx, request.Email); err != nil {
+		ur.l.Error("could not resend verification email ", zap.Error(err))
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "if the account exists and is not verified, a new link was sent"})
+}
+
 func (ur *userRoutes) Login(ctx *gin.Context) {
 	span := opentracing.StartSpan("login handler")
 	defer span.Finish()
@@ -114,7 +291,20 @@ func (ur *userRoutes) Login(ctx *gin.Context) {
 		return
 	}
 
-	token, err := ur.u.Login(context, loginRequest.Email, loginRequest.Password)
+	token, err := ur.u.Login(context, loginRequest.Email, loginRequest.Password, requestOrigin(ctx))
+	if loginThrottled(ctx, err) {
+		return
+	}
+	if errors.Is(err, usecase.ErrInvalidCredentials) {
+		errorResponse(ctx, http.StatusUnauthorized, err.Error())
+
+		return
+	}
+	if errors.Is(err, usecase.ErrEmailNotVerified) {
+		errorResponse(ctx, http.StatusForbidden, err.Error())
+
+		return
+	}
 	if err != nil {
 		ur.l.Error("could not login ", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, err)
@@ -122,6 +312,12 @@ func (ur *userRoutes) Login(ctx *gin.Context) {
 		return
 	}
 
+	if token.MFARequired {
+		ctx.JSON(http.StatusOK, token)
+
+		return
+	}
+
 	ctx.SetCookie("access_token", token.AccessToken, 3600, "/", "localhost", false, true)
 	ctx.SetCookie("refresh_token", token.RefreshToken, 3600, "/", "localhost", false, true)
 
@@ -139,6 +335,11 @@ func (ur *userRoutes) GetUserByEmail(ctx *gin.Context) {
 
 	if user == nil {
 		user, err = ur.u.GetUserByEmail(ctx, email)
+		if errors.Is(err, drivers.ErrUserNotFound) {
+			errorResponse(ctx, http.StatusNotFound, err.Error())
+
+			return
+		}
 		if err != nil {
 			ur.l.Error("http - v1 - user - all", zap.Error(err))
 			errorResponse(ctx, http.StatusInternalServerError, "database problems")
@@ -152,66 +353,78 @@ func (ur *userRoutes) GetUserByEmail(ctx *gin.Context) {
 		}
 	}
 
-	ctx.JSON(http.StatusOK, user)
+	ctx.JSON(http.StatusOK, userInfo(user))
 }
 
+// Refresh exchanges the refresh token from the body or the refresh_token cookie for a new token pair.
 func (ur *userRoutes) Refresh(ctx *gin.Context) {
-	userID, ok := ctx.Get("user_id")
-	if !ok {
-		ctx.JSON(http.StatusBadRequest, fmt.Errorf("could not get user id from token"))
+	var refreshRequest dto.RefreshRequest
+
+	if err := ctx.ShouldBindJSON(&refreshRequest); err != nil || refreshRequest.RefreshToken == "" {
+		refreshRequest.RefreshToken, _ = ctx.Cookie("refresh_token")
+	}
+
+	if refreshRequest.RefreshToken == "" {
+		errorResponse(ctx, http.StatusBadRequest, "refresh token is required")
 
 		return
 	}
 
-	user, err := ur.u.GetUserByID(ctx, int(userID.(float64)))
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err)
+	token, err := ur.u.Refresh(ctx, refreshRequest.RefreshToken)
+	if errors.Is(err, usecase.ErrInvalidRefreshToken) || errors.Is(err, usecase.ErrRefreshTokenReused) {
+		errorResponse(ctx, http.StatusUnauthorized, err.Error())
 
 		return
 	}
-	//не изменились ли роли
-	if user == nil {
+	if err != nil {
+		ur.l.Error("could not refresh tokens ", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, err)
 
 		return
 	}
 
-	accessTokenClaims := jwt.MapClaims{
-		"user_id": user.Id,
-		"email":   user.Email,
-		"name":    user.Name,
-		"exp":     time.Now().Add(time.Second * usecase.AccessTokenTTL).Unix(),
-	}
+	ctx.SetCookie("access_token", token.AccessToken, 3600, "/", "localhost", false, true)
+	ctx.SetCookie("refresh_token", token.RefreshToken, 3600, "/", "localhost", false, true)
 
-	accessToken := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), accessTokenClaims)
+	ctx.JSON(http.StatusOK, token)
+}
 
-	accessTokenString, err := accessToken.SignedString([]byte(ur.cfg.SecretKey))
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err)
+// Logout godoc
+// @Summary logout
+// @Description revokes the current session, or every session of the user when all_sessions is set
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param        request  body  dto.LogoutRequest  false  "Logout scope"
+// @Success      200
+// @Failure      401
+// @Failure      500  {object}  v1.response
+// @Router       /user/logout [post]
+func (ur *userRoutes) Logout(ctx *gin.Context) {
+	var logoutRequest dto.LogoutRequest
 
-		return
-	}
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&logoutRequest); err != nil {
+			errorResponse(ctx, http.StatusBadRequest, "invalid request body")
 
-	refreshTokenClaims := jwt.MapClaims{
-		"user_id": user.Id,
-		"exp":     time.Now().Add(time.Second * usecase.RefreshTokenTTL),
+			return
+		}
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), refreshTokenClaims)
+	claims := ctx.MustGet("claims").(jwt.MapClaims)
 
-	refreshTokenString, err := refreshToken.SignedString([]byte(ur.cfg.SecretKey))
+	err := ur.u.Logout(ctx, claims, logoutRequest.AllSessions)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err)
+		ur.l.Error("could not logout ", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "could not logout")
 
 		return
 	}
 
-	ctx.JSON(http.StatusOK, dto.LoginResponse{
-		Name:         user.Name,
-		Email:        user.Email,
-		AccessToken:  accessTokenString,
-		RefreshToken: refreshTokenString,
-	})
+	ctx.SetCookie("access_token", "", -1, "/", "localhost", false, true)
+	ctx.SetCookie("refresh_token", "", -1, "/", "localhost", false, true)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "user successfully logged out"})
 }
 
 // GetUserByID godoc
@@ -223,6 +436,8 @@ func (ur *userRoutes) Refresh(ctx *gin.Context) {
 // @Param        id   path      int  true  "User ID"
 // @Success      200  {object}  dto.UserInfo
 // @Failure      400  {object}  v1.response
+// @Failure      401
+// @Failure      403
 // @Failure      404  {object}  v1.response
 // @Failure      500  {object}  v1.response
 // @Router       /admin/user/{id} [get]
@@ -245,6 +460,11 @@ func (ur *userRoutes) GetUserByID(ctx *gin.Context) {
 	context := opentracing.ContextWithSpan(ctx.Request.Context(), span)
 
 	user, err := ur.u.GetUserByID(context, id)
+	if errors.Is(err, drivers.ErrUserNotFound) {
+		errorResponse(ctx, http.StatusNotFound, err.Error())
+
+		return
+	}
 	if err != nil {
 		ur.l.Error("http - v1 - user - all ", zap.Error(err))
 		errorResponse(ctx, http.StatusInternalServerError, "database problems")
@@ -252,12 +472,132 @@ func (ur *userRoutes) GetUserByID(ctx *gin.Context) {
 		return
 	}
 
-	userDto := dto.UserInfo{
-		Id:    user.Id,
-		Name:  user.Name,
-		Email: user.Email,
-		Age:   user.Age,
+	ctx.JSON(http.StatusOK, userInfo(user))
+}
+
+// SetRoles godoc
+// @Summary set user roles
+// @Description replaces the roles and permissions of the user and ends all of their sessions
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param        id       path  int                     true  "User ID"
+// @Param        request  body  dto.UpdateRolesRequest  true  "Roles"
+// @Success      204
+// @Failure      400  {object}  v1.response
+// @Failure      401
+// @Failure      403
+// @Failure      404  {object}  v1.response
+// @Failure      500  {object}  v1.response
+// @Router       /admin/user/{id}/roles [put]
+func (ur *userRoutes) SetRoles(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		errorResponse(ctx, http.StatusBadRequest, "id is incorrect")
+
+		return
+	}
+
+	var request dto.UpdateRolesRequest
+
+	if err = ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
 	}
 
-	ctx.JSON(http.StatusOK, userDto)
+	err = ur.u.SetRoles(ctx, id, request.Roles, request.Permissions)
+	switch {
+	case err == nil:
+	case errors.Is(err, usecase.ErrUnknownRole):
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	case errors.Is(err, drivers.ErrUserNotFound):
+		errorResponse(ctx, http.StatusNotFound, err.Error())
+
+		return
+	default:
+		ur.l.Error("http - v1 - user - set roles", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "database problems")
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// UnlockUser godoc
+// @Summary unlock user
+// @Description lifts the lockout after failed logins and forgets the failures of the account
+// @Tags users
+// @Param        id   path  int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  v1.response
+// @Failure      401
+// @Failure      403
+// @Failure      404  {object}  v1.response
+// @Failure      500  {object}  v1.response
+// @Router       /admin/user/{id}/unlock [post]
+func (ur *userRoutes) UnlockUser(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		errorResponse(ctx, http.StatusBadRequest, "id is incorrect")
+
+		return
+	}
+
+	err = ur.u.UnlockUser(ctx, id)
+	if errors.Is(err, drivers.ErrUserNotFound) {
+		errorResponse(ctx, http.StatusNotFound, err.Error())
+
+		return
+	}
+	if err != nil {
+		ur.l.Error("http - v1 - user - unlock", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "database problems")
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// requestOrigin describes where a login request comes from for its session record.
+func requestOrigin(ctx *gin.Context) entity.Origin {
+	return entity.Origin{IP: ctx.ClientIP(), UserAgent: ctx.Request.UserAgent()}
+}
+
+// loginThrottled answers logins rejected by the brute-force protection with
+// Retry-After: 423 for a locked account, 429 while backing off.
+func loginThrottled(ctx *gin.Context, err error) bool {
+	var retryErr *usecase.RetryAfterError
+	if !errors.As(err, &retryErr) {
+		return false
+	}
+
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryErr.RetryAfter.Seconds()))))
+
+	if errors.Is(err, usecase.ErrAccountLocked) {
+		errorResponse(ctx, http.StatusLocked, err.Error())
+	} else {
+		errorResponse(ctx, http.StatusTooManyRequests, err.Error())
+	}
+
+	return true
+}
+
+// userInfo strips the password hash before a user is returned by the api.
+func userInfo(user *entity.User) dto.UserInfo {
+	return dto.UserInfo{
+		Id:            user.Id,
+		Name:          user.Name,
+		Email:         user.Email,
+		Age:           user.Age,
+		Roles:         user.Roles,
+		Permissions:   user.Permissions,
+		MFAEnabled:    user.MFAEnabled,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     user.CreatedAt,
+	}
 }
@@ -0,0 +1,367 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/internal/usecase"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+type webhookRoutes struct {
+	w usecase.WebhookUseCase
+	l *logger.Logger
+}
+
+func newWebhookRoutes(handler *gin.RouterGroup, w usecase.WebhookUseCase, l *logger.Logger) {
+	r := &webhookRoutes{w, l}
+
+	adminHandler := handler.Group("/webhooks")
+	{
+		adminHandler.GET("", r.GetWebhooks)
+		adminHandler.GET("/:id", r.GetWebhook)
+		adminHandler.POST("", r.CreateWebhook)
+		adminHandler.PUT("/:id", r.UpdateWebhook)
+		adminHandler.DELETE("/:id", r.DeleteWebhook)
+		adminHandler.POST("/:id/secret", r.RotateSecret)
+		adminHandler.GET("/:id/deliveries", r.GetDeliveries)
+		adminHandler.GET("/:id/deliveries/:delivery_id", r.GetDelivery)
+		adminHandler.POST("/:id/deliveries/:delivery_id/replay", r.ReplayDelivery)
+	}
+}
+
+// GetWebhooks godoc
+// @Summary list webhook endpoints
+// @Tags webhooks
+// @Produce json
+// @Success      200  {array}   dto.WebhookInfo
+// @Failure      401
+// @Failure      403
+// @Failure      500  {object}  v1.response
+// @Router       /admin/webhooks [get]
+func (wr *webhookRoutes) GetWebhooks(ctx *gin.Context) {
+	endpoints, err := wr.w.Webhooks(ctx)
+	if err != nil {
+		wr.l.Error("http - v1 - webhooks - list", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "database problems")
+
+		return
+	}
+
+	response := make([]dto.WebhookInfo, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		response = append(response, webhookInfo(endpoint))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetWebhook godoc
+// @Summary get webhook endpoint
+// @Tags webhooks
+// @Produce json
+// @Param        id  path  int  true  "Webhook ID"
+// @Success      200  {object}  dto.WebhookInfo
+// @Failure      400  {object}  v1.response
+// @Failure      404  {object}  v1.response
+// @Router       /admin/webhooks/{id} [get]
+func (wr *webhookRoutes) GetWebhook(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id")
+	if !ok {
+		return
+	}
+
+	endpoint, err := wr.w.GetWebhook(ctx, id)
+	if err != nil {
+		wr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhookInfo(endpoint))
+}
+
+// CreateWebhook godoc
+// @Summary register webhook endpoint
+// @Description the signing secret is only returned once
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param        request  body  dto.CreateWebhookRequest  true  "Endpoint"
+// @Success      201  {object}  dto.WebhookCredentials
+// @Failure      400  {object}  v1.response
+// @Router       /admin/webhooks [post]
+func (wr *webhookRoutes) CreateWebhook(ctx *gin.Context) {
+	var request dto.CreateWebhookRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	endpoint, err := wr.w.CreateWebhook(ctx, &request)
+	if err != nil {
+		wr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, dto.WebhookCredentials{WebhookInfo: webhookInfo(endpoint), Secret: endpoint.Secret})
+}
+
+// UpdateWebhook godoc
+// @Summary update webhook endpoint
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param        id       path  int                       true  "Webhook ID"
+// @Param        request  body  dto.UpdateWebhookRequest  true  "Endpoint"
+// @Success      200  {object}  dto.WebhookInfo
+// @Failure      400  {object}  v1.response
+// @Failure      404  {object}  v1.response
+// @Router       /admin/webhooks/{id} [put]
+func (wr *webhookRoutes) UpdateWebhook(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var request dto.UpdateWebhookRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	endpoint, err := wr.w.UpdateWebhook(ctx, id, &request)
+	if err != nil {
+		wr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhookInfo(endpoint))
+}
+
+// DeleteWebhook godoc
+// @Summary delete webhook endpoint
+// @Description its deliveries are deleted too
+// @Tags webhooks
+// @Param        id  path  int  true  "Webhook ID"
+// @Success      204
+// @Failure      400  {object}  v1.response
+// @Failure      404  {object}  v1.response
+// @Router       /admin/webhooks/{id} [delete]
+func (wr *webhookRoutes) DeleteWebhook(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id")
+	if !ok {
+		return
+	}
+
+	if err := wr.w.DeleteWebhook(ctx, id); err != nil {
+		wr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RotateSecret godoc
+// @Summary rotate webhook signing secret
+// @Description deliveries are signed with the new secret from now on
+// @Tags webhooks
+// @Produce json
+// @Param        id  path  int  true  "Webhook ID"
+// @Success      200  {object}  dto.WebhookCredentials
+// @Failure      400  {object}  v1.response
+// @Failure      404  {object}  v1.response
+// @Router       /admin/webhooks/{id}/secret [post]
+func (wr *webhookRoutes) RotateSecret(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id")
+	if !ok {
+		return
+	}
+
+	endpoint, err := wr.w.RotateWebhookSecret(ctx, id)
+	if err != nil {
+		wr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.WebhookCredentials{WebhookInfo: webhookInfo(endpoint), Secret: endpoint.Secret})
+}
+
+// GetDeliveries godoc
+// @Summary list webhook deliveries
+// @Description returns a page of the deliveries to the endpoint, newest first
+// @Tags webhooks
+// @Produce json
+// @Param        id      path   int     true   "Webhook ID"
+// @Param        status  query  string  false  "pending, succeeded or failed"
+// @Param        limit   query  int     false  "Page size, 50 by default and at most 500"
+// @Param        offset  query  int     false  "Deliveries to skip"
+// @Success      200  {object}  dto.WebhookDeliveriesResponse
+// @Failure      400  {object}  v1.response
+// @Failure      404  {object}  v1.response
+// @Router       /admin/webhooks/{id}/deliveries [get]
+func (wr *webhookRoutes) GetDeliveries(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var request dto.WebhookDeliveriesRequest
+
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	deliveries, total, err := wr.w.Deliveries(ctx, id, &request)
+	if err != nil {
+		wr.fail(ctx, err)
+
+		return
+	}
+
+	response := dto.WebhookDeliveriesResponse{
+		Deliveries: make([]dto.WebhookDelivery, 0, len(deliveries)),
+		Total:      total,
+		Limit:      request.Limit,
+		Offset:     request.Offset,
+	}
+	for _, delivery := range deliveries {
+		response.Deliveries = append(response.Deliveries, webhookDelivery(delivery))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetDelivery godoc
+// @Summary get webhook delivery
+// @Tags webhooks
+// @Produce json
+// @Param        id           path  int  true  "Webhook ID"
+// @Param        delivery_id  path  int  true  "Delivery ID"
+// @Success      200  {object}  dto.WebhookDelivery
+// @Failure      400  {object}  v1.response
+// @Failure      404  {object}  v1.response
+// @Router       /admin/webhooks/{id}/deliveries/{delivery_id} [get]
+func (wr *webhookRoutes) GetDelivery(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := pathID(ctx, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := wr.w.GetDelivery(ctx, id, deliveryID)
+	if err != nil {
+		wr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhookDelivery(delivery))
+}
+
+// ReplayDelivery godoc
+// @Summary replay webhook delivery
+// @Description sends the delivery again right away and returns the outcome
+// @Tags webhooks
+// @Produce json
+// @Param        id           path  int  true  "Webhook ID"
+// @Param        delivery_id  path  int  true  "Delivery ID"
+// @Success      200  {object}  dto.WebhookDelivery
+// @Failure      400  {object}  v1.response
+// @Failure      404  {object}  v1.response
+// @Router       /admin/webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func (wr *webhookRoutes) ReplayDelivery(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := pathID(ctx, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := wr.w.ReplayDelivery(ctx, id, deliveryID)
+	if err != nil {
+		wr.fail(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhookDelivery(delivery))
+}
+
+func (wr *webhookRoutes) fail(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, drivers.ErrWebhookNotFound), errors.Is(err, drivers.ErrDeliveryNotFound):
+		errorResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, usecase.ErrInvalidWebhook):
+		errorResponse(ctx, http.StatusBadRequest, err.Error())
+	default:
+		wr.l.Error("http - v1 - webhooks", zap.Error(err))
+		errorResponse(ctx, http.StatusInternalServerError, "database problems")
+	}
+}
+
+// pathID parses a numeric path parameter, answering 400 when it is not one.
+func pathID(ctx *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(ctx.Param(name))
+	if err != nil {
+		errorResponse(ctx, http.StatusBadRequest, name+" is incorrect")
+
+		return 0, false
+	}
+	return id, true
+}
+
+func webhookInfo(endpoint *entity.WebhookEndpoint) dto.WebhookInfo {
+	return dto.WebhookInfo{
+		ID:          endpoint.Id,
+		URL:         endpoint.URL,
+		Description: endpoint.Description,
+		EventTypes:  endpoint.EventTypes,
+		Enabled:     endpoint.Enabled,
+		CreatedAt:   endpoint.CreatedAt,
+		UpdatedAt:   endpoint.UpdatedAt,
+	}
+}
+
+func webhookDelivery(delivery *entity.WebhookDelivery) dto.WebhookDelivery {
+	info := dto.WebhookDelivery{
+		ID:             delivery.Id,
+		EndpointID:     delivery.EndpointID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Payload:        json.RawMessage(delivery.Payload),
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		ResponseBody:   delivery.ResponseBody,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+	// only pending deliveries are scheduled
+	if delivery.Status == entity.DeliveryPending {
+		info.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	return info
+}
@@ -3,6 +3,7 @@ package drivers
 import (
 	"context"
 	"github.com/madyar997/sso-jcode/internal/entity"
+	"time"
 )
 
 type DataStore interface {
@@ -10,11 +11,166 @@ type DataStore interface {
 	Close() error
 	Connect() error
 	UserRepo
+	ClientRepo
+	PasskeyRepo
+	SessionRepo
+	AuditRepo
+	OutboxRepo
+	WebhookRepo
 }
 
+// UserRepo lookups and updates skip soft-deleted users, they answer
+// ErrUserNotFound for them.
 type UserRepo interface {
-	GetUsers(ctx context.Context) ([]*entity.User, error)
+	// GetUsers returns a page of the users matching the query and how many
+	// match it in total.
+	GetUsers(ctx context.Context, query *SearchQuery) ([]*entity.User, int64, error)
 	GetUserByID(ctx context.Context, id int) (user *entity.User, err error)
-	CreateUser(ctx context.Context, user *entity.User) (int, error)
+	// CreateUser stores the user and the outbox events about it in one
+	// transaction. The events get the id of the new user as AggregateID.
+	CreateUser(ctx context.Context, user *entity.User, events ...*entity.OutboxEvent) (int, error)
 	GetUserByEmail(ctx context.Context, email string) (*entity.User, error)
+	// GetAnyUserByID finds the user whether soft-deleted or not.
+	GetAnyUserByID(ctx context.Context, id int) (*entity.User, error)
+	// UpdateUser replaces the name, email, age and email verification state
+	// of the user. Like every write below it stores the events in the same
+	// transaction.
+	UpdateUser(ctx context.Context, user *entity.User, events ...*entity.OutboxEvent) error
+	// PatchUser changes only the fields set in the patch.
+	PatchUser(ctx context.Context, id int, patch *entity.UserPatch, events ...*entity.OutboxEvent) error
+	// DeleteUser soft-deletes the user, the row stays and keeps its email.
+	DeleteUser(ctx context.Context, id int, deletedAt time.Time, events ...*entity.OutboxEvent) error
+	// RestoreUser undoes DeleteUser, ErrUserNotFound means there is no
+	// soft-deleted user with the id.
+	RestoreUser(ctx context.Context, id int, events ...*entity.OutboxEvent) error
+	// HardDeleteUser removes the user, deleted or not, with its sessions and passkeys.
+	HardDeleteUser(ctx context.Context, id int, events ...*entity.OutboxEvent) error
+	UpdateUserRoles(ctx context.Context, id int, roles, permissions []string) error
+	UpdateUserMFA(ctx context.Context, id int, totpSecret string, enabled bool, recoveryCodes []string) error
+	// RemoveRecoveryCode spends a recovery code in a single conditional write
+	// and reports whether the user still held it.
+	RemoveRecoveryCode(ctx context.Context, id int, codeHash string) (bool, error)
+	UpdateUserEmailVerified(ctx context.Context, id int, verified bool) error
+	// UpdateUserPassword replaces the password hash and the history of previous hashes.
+	UpdateUserPassword(ctx context.Context, id int, passwordHash string, history []string) error
+}
+
+type ClientRepo interface {
+	GetClients(ctx context.Context) ([]*entity.Client, error)
+	GetClientByClientID(ctx context.Context, clientID string) (*entity.Client, error)
+	CreateClient(ctx context.Context, client *entity.Client) (int, error)
+	UpdateClient(ctx context.Context, client *entity.Client) error
+	DeleteClient(ctx context.Context, clientID string) error
+}
+
+type PasskeyRepo interface {
+	GetPasskeys(ctx context.Context, userID int) ([]*entity.Passkey, error)
+	GetPasskeyByCredentialID(ctx context.Context, credentialID string) (*entity.Passkey, error)
+	CreatePasskey(ctx context.Context, passkey *entity.Passkey) (int, error)
+	// UpdatePasskeyUsage stores the sign count and backup state of the last login.
+	UpdatePasskeyUsage(ctx context.Context, passkey *entity.Passkey) error
+	DeletePasskey(ctx context.Context, userID int, credentialID string) error
+}
+
+type SessionRepo interface {
+	// GetSessions returns the sessions of the user that are neither revoked nor expired.
+	GetSessions(ctx context.Context, userID int) ([]*entity.Session, error)
+	GetSession(ctx context.Context, id string) (*entity.Session, error)
+	CreateSession(ctx context.Context, session *entity.Session) error
+	// TouchSession records a token refresh of the session.
+	TouchSession(ctx context.Context, id string, lastSeenAt, expiresAt time.Time) error
+	RevokeSession(ctx context.Context, id string, revokedAt time.Time) error
+	// RevokeUserSessions marks every session of the user that is still active as revoked.
+	RevokeUserSessions(ctx context.Context, userID int, revokedAt time.Time) error
+}
+
+type AuditRepo interface {
+	CreateAuditEvent(ctx context.Context, event *entity.AuditEvent) error
+	// GetAuditEvents returns a page of the events matching the query, newest
+	// first, and the number of all matching events.
+	GetAuditEvents(ctx context.Context, query *AuditQuery) ([]*entity.AuditEvent, int64, error)
+}
+
+type OutboxRepo interface {
+	// ClaimOutboxEvents returns up to limit unpublished events due at now and
+	// postpones their next attempt to lockedUntil, so that other relays skip
+	// them while they are being published.
+	ClaimOutboxEvents(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*entity.OutboxEvent, error)
+	MarkOutboxEventPublished(ctx context.Context, id int, publishedAt time.Time) error
+	// RetryOutboxEvent counts a failed attempt and schedules the next one.
+	RetryOutboxEvent(ctx context.Context, id int, lastError string, nextAttemptAt time.Time) error
+	// DeletePublishedOutboxEvents drops the events published before the given time.
+	DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+type WebhookRepo interface {
+	GetWebhookEndpoints(ctx context.Context) ([]*entity.WebhookEndpoint, error)
+	GetWebhookEndpoint(ctx context.Context, id int) (*entity.WebhookEndpoint, error)
+	CreateWebhookEndpoint(ctx context.Context, endpoint *entity.WebhookEndpoint) (int, error)
+	UpdateWebhookEndpoint(ctx context.Context, endpoint *entity.WebhookEndpoint) error
+	// DeleteWebhookEndpoint removes the endpoint together with its deliveries.
+	DeleteWebhookEndpoint(ctx context.Context, id int) error
+
+	// CreateWebhookDeliveries stores new deliveries, skipping those that
+	// already exist for the same endpoint and event.
+	CreateWebhookDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error
+	// GetWebhookDeliveries returns a page of the deliveries matching the
+	// query, newest first, and the number of all matching deliveries.
+	GetWebhookDeliveries(ctx context.Context, query *WebhookDeliveryQuery) ([]*entity.WebhookDelivery, int64, error)
+	GetWebhookDelivery(ctx context.Context, id int) (*entity.WebhookDelivery, error)
+	// ClaimWebhookDeliveries works like ClaimOutboxEvents for pending deliveries.
+	ClaimWebhookDeliveries(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*entity.WebhookDelivery, error)
+	// UpdateWebhookDelivery stores the outcome of a delivery attempt.
+	UpdateWebhookDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+}
+
+// WebhookDeliveryQuery filters deliveries, zero fields match everything.
+type WebhookDeliveryQuery struct {
+	EndpointID int
+	Status     string
+	Limit      int
+	Offset     int
+}
+
+// Sort orders of a SearchQuery.
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// UserSortFields are the fields users can be sorted by.
+var UserSortFields = []string{"id", "name", "email", "age", "created_at"}
+
+// SearchQuery filters, sorts and pages users, zero fields match everything.
+// Email and Name match substrings regardless of case, the age bounds and
+// CreatedFrom are inclusive, CreatedTo is exclusive. Users with the same
+// value of SortBy are ordered by id.
+//
+// With AfterID set the page continues after that user, whose SortBy field
+// is AfterValue, and Offset is not used.
+type SearchQuery struct {
+	Email       string
+	Name        string
+	MinAge      *int
+	MaxAge      *int
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Limit       int
+	Offset      int
+	SortBy      string
+	SortOrder   string
+	AfterID     int
+	AfterValue  interface{}
+}
+
+// AuditQuery filters audit events, zero fields match everything.
+type AuditQuery struct {
+	Type     string
+	ActorID  int
+	TargetID int
+	IP       string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
 }
@@ -2,4 +2,16 @@ package drivers
 
 import "errors"
 
-var ErrInvalidConfigStruct = errors.New("invalid config structure")
+var (
+	ErrInvalidConfigStruct  = errors.New("invalid config structure")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrUserAlreadyExists    = errors.New("user with this email already exists")
+	ErrClientNotFound       = errors.New("client not found")
+	ErrClientAlreadyExists  = errors.New("client with this client_id already exists")
+	ErrPasskeyNotFound      = errors.New("passkey not found")
+	ErrPasskeyAlreadyExists = errors.New("passkey is already registered")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrOutboxEventNotFound  = errors.New("outbox event not found")
+	ErrWebhookNotFound      = errors.New("webhook endpoint not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+)
@@ -0,0 +1,72 @@
+package mongo
+
+import (
+	"context"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const auditEventsCollection = "audit_events"
+
+func (m *Mongo) CreateAuditEvent(ctx context.Context, event *entity.AuditEvent) error {
+	id, err := m.nextID(ctx, auditEventsCollection)
+	if err != nil {
+		return err
+	}
+
+	event.Id = id
+	_, err = m.DB.Collection(auditEventsCollection).InsertOne(ctx, event)
+	return err
+}
+
+func (m *Mongo) GetAuditEvents(ctx context.Context, query *drivers.AuditQuery) ([]*entity.AuditEvent, int64, error) {
+	filter := bson.M{}
+	if query.Type != "" {
+		filter["type"] = query.Type
+	}
+	if query.ActorID != 0 {
+		filter["actor_id"] = query.ActorID
+	}
+	if query.TargetID != 0 {
+		filter["target_id"] = query.TargetID
+	}
+	if query.IP != "" {
+		filter["ip"] = query.IP
+	}
+	created := bson.M{}
+	if !query.From.IsZero() {
+		created["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		created["$lt"] = query.To
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+
+	collection := m.DB.Collection(auditEventsCollection)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(query.Offset)).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	events := make([]*entity.AuditEvent, 0)
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
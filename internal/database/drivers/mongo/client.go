@@ -0,0 +1,88 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const clientsCollection = "clients"
+
+func (m *Mongo) GetClients(ctx context.Context) ([]*entity.Client, error) {
+	cursor, err := m.DB.Collection(clientsCollection).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	clients := make([]*entity.Client, 0)
+	if err = cursor.All(ctx, &clients); err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+func (m *Mongo) GetClientByClientID(ctx context.Context, clientID string) (*entity.Client, error) {
+	client := new(entity.Client)
+	err := m.DB.Collection(clientsCollection).FindOne(ctx, bson.M{"client_id": clientID}).Decode(client)
+	switch {
+	case err == nil:
+		return client, nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return nil, drivers.ErrClientNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *Mongo) CreateClient(ctx context.Context, client *entity.Client) (int, error) {
+	id, err := m.nextID(ctx, clientsCollection)
+	if err != nil {
+		return 0, err
+	}
+
+	client.Id = id
+	_, err = m.DB.Collection(clientsCollection).InsertOne(ctx, client)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return 0, drivers.ErrClientAlreadyExists
+		}
+		return 0, err
+	}
+	return client.Id, nil
+}
+
+func (m *Mongo) UpdateClient(ctx context.Context, client *entity.Client) error {
+	res, err := m.DB.Collection(clientsCollection).UpdateOne(ctx,
+		bson.M{"client_id": client.ClientID},
+		bson.M{"$set": bson.M{
+			"secret_hash":   client.SecretHash,
+			"name":          client.Name,
+			"redirect_uris": client.RedirectURIs,
+			"scopes":        client.Scopes,
+			"grant_types":   client.GrantTypes,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrClientNotFound
+	}
+	return nil
+}
+
+func (m *Mongo) DeleteClient(ctx context.Context, clientID string) error {
+	res, err := m.DB.Collection(clientsCollection).DeleteOne(ctx, bson.M{"client_id": clientID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return drivers.ErrClientNotFound
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package mongo
 import (
 	"context"
 	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -39,7 +40,7 @@ func New(conf map[string]string) (drivers.DataStore, error) {
 		return nil, drivers.ErrInvalidConfigStruct
 	}
 
-	if _, ok := (conf)["db"]; !ok {
+	if db, ok := (conf)["db"]; !ok || db == "" {
 		return nil, drivers.ErrInvalidConfigStruct
 	}
 
@@ -70,7 +71,11 @@ func (m *Mongo) Connect() error {
 	m.DB = m.client.Database(m.dbname)
 
 	// убеждаемся что созданы все необходимые индексы
-	return m.ensureIndexes()
+	if err := m.ensureIndexes(); err != nil {
+		return err
+	}
+
+	return m.backfillUsers()
 }
 
 func (m *Mongo) Ping() error {
@@ -86,8 +91,78 @@ func (m *Mongo) Close() error {
 
 // убеждается что все индексы построены
 func (m *Mongo) ensureIndexes() error {
-	//ctx, cancel := context.WithTimeout(context.Background(), m.connectionTimeout)
-	//defer cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), m.ensureIdxTimeout)
+	defer cancel()
+
+	_, err := m.DB.Collection(usersCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.Collection(clientsCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "client_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.Collection(passkeysCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "credential_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.Collection(sessionsCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.Collection(auditEventsCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}}},
+		{Keys: bson.D{{Key: "target_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.Collection(outboxEventsCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "published_at", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.Collection(webhookDeliveriesCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "endpoint_id", Value: 1}, {Key: "event_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "endpoint_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+
+	return err
+}
+
+// backfillUsers gives users stored before created_at existed the time of the
+// upgrade, like the postgres migration does. Without it they would be left
+// out of listings sorted or filtered by creation time.
+func (m *Mongo) backfillUsers() error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.ensureIdxTimeout)
+	defer cancel()
 
-	return nil
+	_, err := m.DB.Collection(usersCollection).UpdateMany(ctx,
+		bson.M{"created_at": nil},
+		bson.M{"$set": bson.M{"created_at": time.Now().UTC()}},
+	)
+	return err
 }
@@ -0,0 +1,80 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const outboxEventsCollection = "outbox_events"
+
+// ClaimOutboxEvents claims the events one by one, every find and update is
+// atomic so relays running side by side never get the same event.
+func (m *Mongo) ClaimOutboxEvents(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*entity.OutboxEvent, error) {
+	collection := m.DB.Collection(outboxEventsCollection)
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"_id": 1}).
+		SetReturnDocument(options.After)
+
+	events := make([]*entity.OutboxEvent, 0, limit)
+	for len(events) < limit {
+		event := new(entity.OutboxEvent)
+		err := collection.FindOneAndUpdate(ctx,
+			bson.M{"published_at": nil, "next_attempt_at": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"next_attempt_at": lockedUntil}},
+			opts,
+		).Decode(event)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (m *Mongo) MarkOutboxEventPublished(ctx context.Context, id int, publishedAt time.Time) error {
+	res, err := m.DB.Collection(outboxEventsCollection).UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"published_at": publishedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrOutboxEventNotFound
+	}
+	return nil
+}
+
+func (m *Mongo) RetryOutboxEvent(ctx context.Context, id int, lastError string, nextAttemptAt time.Time) error {
+	res, err := m.DB.Collection(outboxEventsCollection).UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$inc": bson.M{"attempts": 1},
+			"$set": bson.M{"last_error": lastError, "next_attempt_at": nextAttemptAt},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrOutboxEventNotFound
+	}
+	return nil
+}
+
+func (m *Mongo) DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	res, err := m.DB.Collection(outboxEventsCollection).DeleteMany(ctx, bson.M{"published_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
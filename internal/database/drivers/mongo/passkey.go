@@ -0,0 +1,86 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const passkeysCollection = "passkeys"
+
+func (m *Mongo) GetPasskeys(ctx context.Context, userID int) ([]*entity.Passkey, error) {
+	cursor, err := m.DB.Collection(passkeysCollection).Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	passkeys := make([]*entity.Passkey, 0)
+	if err = cursor.All(ctx, &passkeys); err != nil {
+		return nil, err
+	}
+	return passkeys, nil
+}
+
+func (m *Mongo) GetPasskeyByCredentialID(ctx context.Context, credentialID string) (*entity.Passkey, error) {
+	passkey := new(entity.Passkey)
+	err := m.DB.Collection(passkeysCollection).FindOne(ctx, bson.M{"credential_id": credentialID}).Decode(passkey)
+	switch {
+	case err == nil:
+		return passkey, nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return nil, drivers.ErrPasskeyNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *Mongo) CreatePasskey(ctx context.Context, passkey *entity.Passkey) (int, error) {
+	id, err := m.nextID(ctx, passkeysCollection)
+	if err != nil {
+		return 0, err
+	}
+
+	passkey.Id = id
+	_, err = m.DB.Collection(passkeysCollection).InsertOne(ctx, passkey)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return 0, drivers.ErrPasskeyAlreadyExists
+		}
+		return 0, err
+	}
+	return passkey.Id, nil
+}
+
+func (m *Mongo) UpdatePasskeyUsage(ctx context.Context, passkey *entity.Passkey) error {
+	res, err := m.DB.Collection(passkeysCollection).UpdateOne(ctx,
+		bson.M{"credential_id": passkey.CredentialID},
+		bson.M{"$set": bson.M{
+			"sign_count":   passkey.SignCount,
+			"backup_state": passkey.BackupState,
+			"last_used_at": passkey.LastUsedAt,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrPasskeyNotFound
+	}
+	return nil
+}
+
+func (m *Mongo) DeletePasskey(ctx context.Context, userID int, credentialID string) error {
+	res, err := m.DB.Collection(passkeysCollection).DeleteOne(ctx, bson.M{"user_id": userID, "credential_id": credentialID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return drivers.ErrPasskeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const sessionsCollection = "sessions"
+
+func (m *Mongo) GetSessions(ctx context.Context, userID int) ([]*entity.Session, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": nil,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	cursor, err := m.DB.Collection(sessionsCollection).Find(ctx, filter, options.Find().SetSort(bson.M{"last_seen_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := make([]*entity.Session, 0)
+	if err = cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (m *Mongo) GetSession(ctx context.Context, id string) (*entity.Session, error) {
+	session := new(entity.Session)
+	err := m.DB.Collection(sessionsCollection).FindOne(ctx, bson.M{"_id": id}).Decode(session)
+	switch {
+	case err == nil:
+		return session, nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return nil, drivers.ErrSessionNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *Mongo) CreateSession(ctx context.Context, session *entity.Session) error {
+	_, err := m.DB.Collection(sessionsCollection).InsertOne(ctx, session)
+	return err
+}
+
+func (m *Mongo) TouchSession(ctx context.Context, id string, lastSeenAt, expiresAt time.Time) error {
+	res, err := m.DB.Collection(sessionsCollection).UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"last_seen_at": lastSeenAt, "expires_at": expiresAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (m *Mongo) RevokeSession(ctx context.Context, id string, revokedAt time.Time) error {
+	res, err := m.DB.Collection(sessionsCollection).UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": revokedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (m *Mongo) RevokeUserSessions(ctx context.Context, userID int, revokedAt time.Time) error {
+	_, err := m.DB.Collection(sessionsCollection).UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": revokedAt}},
+	)
+	return err
+}
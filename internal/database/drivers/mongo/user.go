@@ -2,25 +2,402 @@ package mongo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
 	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/opentracing/opentracing-go"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"time"
 )
 
-func (m *Mongo) GetUsers(ctx context.Context) ([]*entity.User, error) {
-	//TODO implement me
-	panic("implement me")
+const (
+	usersCollection    = "users"
+	countersCollection = "counters"
+)
+
+type counter struct {
+	ID  string `bson:"_id"`
+	Seq int    `bson:"seq"`
+}
+
+// userSortFields maps the sort fields onto document fields.
+var userSortFields = map[string]string{
+	"id":         "_id",
+	"name":       "name",
+	"email":      "email",
+	"age":        "age",
+	"created_at": "created_at",
+}
+
+func (m *Mongo) GetUsers(ctx context.Context, query *drivers.SearchQuery) ([]*entity.User, int64, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "get users - repo")
+	defer span.Finish()
+
+	field, ok := userSortFields[query.SortBy]
+	if !ok {
+		return nil, 0, fmt.Errorf("users cannot be sorted by %q", query.SortBy)
+	}
+
+	filter := active(bson.M{})
+	if query.Email != "" {
+		filter["email"] = bson.M{"$regex": regexp.QuoteMeta(query.Email), "$options": "i"}
+	}
+	if query.Name != "" {
+		filter["name"] = bson.M{"$regex": regexp.QuoteMeta(query.Name), "$options": "i"}
+	}
+	age := bson.M{}
+	if query.MinAge != nil {
+		age["$gte"] = *query.MinAge
+	}
+	if query.MaxAge != nil {
+		age["$lte"] = *query.MaxAge
+	}
+	if len(age) > 0 {
+		filter["age"] = age
+	}
+	created := bson.M{}
+	if !query.CreatedFrom.IsZero() {
+		created["$gte"] = query.CreatedFrom
+	}
+	if !query.CreatedTo.IsZero() {
+		created["$lt"] = query.CreatedTo
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+
+	collection := m.DB.Collection(usersCollection)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	direction, comparison := 1, "$gt"
+	if query.SortOrder == drivers.SortDesc {
+		direction, comparison = -1, "$lt"
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(query.Limit))
+
+	if query.AfterID != 0 {
+		filter["$or"] = bson.A{
+			bson.M{field: bson.M{comparison: query.AfterValue}},
+			bson.M{field: query.AfterValue, "_id": bson.M{comparison: query.AfterID}},
+		}
+	} else {
+		opts.SetSkip(int64(query.Offset))
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	users := make([]*entity.User, 0)
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
 }
 
 func (m *Mongo) GetUserByID(ctx context.Context, id int) (user *entity.User, err error) {
-	//TODO implement me
-	panic("implement me")
+	span, _ := opentracing.StartSpanFromContext(ctx, "get user by id - repo")
+	defer span.Finish()
+
+	return m.findUser(ctx, active(bson.M{"_id": id}))
 }
 
-func (m *Mongo) CreateUser(ctx context.Context, user *entity.User) (int, error) {
-	//TODO implement me
-	panic("implement me")
+// CreateUser inserts the user and its outbox events in a transaction, which
+// needs mongo to run as a replica set. Ids are taken before it starts, an
+// aborted insert leaves a gap like a serial does.
+func (m *Mongo) CreateUser(ctx context.Context, user *entity.User, events ...*entity.OutboxEvent) (int, error) {
+	id, err := m.nextID(ctx, usersCollection)
+	if err != nil {
+		return 0, err
+	}
+
+	user.Id = id
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now().UTC()
+	}
+	for _, event := range events {
+		event.AggregateID = user.Id
+	}
+
+	err = m.withEvents(ctx, events, func(ctx context.Context) error {
+		_, err := m.DB.Collection(usersCollection).InsertOne(ctx, user)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return user.Id, nil
 }
 
 func (m *Mongo) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
-	//TODO implement me
-	panic("implement me")
+	span, _ := opentracing.StartSpanFromContext(ctx, "get user by email repo")
+	defer span.Finish()
+
+	return m.findUser(ctx, active(bson.M{"email": email}))
+}
+
+func (m *Mongo) GetAnyUserByID(ctx context.Context, id int) (*entity.User, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "get any user by id - repo")
+	defer span.Finish()
+
+	return m.findUser(ctx, bson.M{"_id": id})
+}
+
+func (m *Mongo) UpdateUserRoles(ctx context.Context, id int, roles, permissions []string) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "update user roles - repo")
+	defer span.Finish()
+
+	res, err := m.DB.Collection(usersCollection).UpdateOne(ctx,
+		active(bson.M{"_id": id}),
+		bson.M{"$set": bson.M{"roles": roles, "permissions": permissions}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrUserNotFound
+	}
+	return nil
+}
+
+func (m *Mongo) UpdateUserMFA(ctx context.Context, id int, totpSecret string, enabled bool, recoveryCodes []string) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "update user mfa - repo")
+	defer span.Finish()
+
+	res, err := m.DB.Collection(usersCollection).UpdateOne(ctx,
+		active(bson.M{"_id": id}),
+		bson.M{"$set": bson.M{"totp_secret": totpSecret, "mfa_enabled": enabled, "recovery_codes": recoveryCodes}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrUserNotFound
+	}
+	return nil
+}
+
+func (m *Mongo) RemoveRecoveryCode(ctx context.Context, id int, codeHash string) (bool, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "remove recovery code - repo")
+	defer span.Finish()
+
+	res, err := m.DB.Collection(usersCollection).UpdateOne(ctx,
+		active(bson.M{"_id": id, "recovery_codes": codeHash}),
+		bson.M{"$pull": bson.M{"recovery_codes": codeHash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (m *Mongo) UpdateUserEmailVerified(ctx context.Context, id int, verified bool) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "update user email verified - repo")
+	defer span.Finish()
+
+	res, err := m.DB.Collection(usersCollection).UpdateOne(ctx,
+		active(bson.M{"_id": id}),
+		bson.M{"$set": bson.M{"email_verified": verified}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrUserNotFound
+	}
+	return nil
+}
+
+func (m *Mongo) UpdateUserPassword(ctx context.Context, id int, passwordHash string, history []string) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "update user password - repo")
+	defer span.Finish()
+
+	res, err := m.DB.Collection(usersCollection).UpdateOne(ctx,
+		active(bson.M{"_id": id}),
+		bson.M{"$set": bson.M{"password": passwordHash, "password_history": history}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrUserNotFound
+	}
+	return nil
+}
+
+func (m *Mongo) UpdateUser(ctx context.Context, user *entity.User, events ...*entity.OutboxEvent) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "update user - repo")
+	defer span.Finish()
+
+	return m.withEvents(ctx, events, func(ctx context.Context) error {
+		return m.updateUser(ctx, active(bson.M{"_id": user.Id}), bson.M{"$set": bson.M{
+			"name":           user.Name,
+			"email":          user.Email,
+			"age":            user.Age,
+			"email_verified": user.EmailVerified,
+		}})
+	})
+}
+
+func (m *Mongo) PatchUser(ctx context.Context, id int, patch *entity.UserPatch, events ...*entity.OutboxEvent) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "patch user - repo")
+	defer span.Finish()
+
+	fields := bson.M{}
+	if patch.Name != nil {
+		fields["name"] = *patch.Name
+	}
+	if patch.Email != nil {
+		fields["email"] = *patch.Email
+	}
+	if patch.Age != nil {
+		fields["age"] = *patch.Age
+	}
+	if patch.EmailVerified != nil {
+		fields["email_verified"] = *patch.EmailVerified
+	}
+
+	return m.withEvents(ctx, events, func(ctx context.Context) error {
+		// пустой $set монго не принимает, достаточно проверить что пользователь есть
+		if len(fields) == 0 {
+			_, err := m.findUser(ctx, active(bson.M{"_id": id}))
+			return err
+		}
+		return m.updateUser(ctx, active(bson.M{"_id": id}), bson.M{"$set": fields})
+	})
+}
+
+func (m *Mongo) DeleteUser(ctx context.Context, id int, deletedAt time.Time, events ...*entity.OutboxEvent) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "delete user - repo")
+	defer span.Finish()
+
+	return m.withEvents(ctx, events, func(ctx context.Context) error {
+		return m.updateUser(ctx, active(bson.M{"_id": id}), bson.M{"$set": bson.M{"deleted_at": deletedAt}})
+	})
+}
+
+func (m *Mongo) RestoreUser(ctx context.Context, id int, events ...*entity.OutboxEvent) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "restore user - repo")
+	defer span.Finish()
+
+	return m.withEvents(ctx, events, func(ctx context.Context) error {
+		return m.updateUser(ctx,
+			bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}},
+			bson.M{"$unset": bson.M{"deleted_at": ""}},
+		)
+	})
+}
+
+// HardDeleteUser removes the sessions and passkeys of the user as well, there
+// are no foreign keys to do it.
+func (m *Mongo) HardDeleteUser(ctx context.Context, id int, events ...*entity.OutboxEvent) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "hard delete user - repo")
+	defer span.Finish()
+
+	return m.withEvents(ctx, events, func(ctx context.Context) error {
+		res, err := m.DB.Collection(usersCollection).DeleteOne(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if res.DeletedCount == 0 {
+			return drivers.ErrUserNotFound
+		}
+
+		if _, err = m.DB.Collection(sessionsCollection).DeleteMany(ctx, bson.M{"user_id": id}); err != nil {
+			return err
+		}
+		_, err = m.DB.Collection(passkeysCollection).DeleteMany(ctx, bson.M{"user_id": id})
+		return err
+	})
+}
+
+// withEvents runs write and inserts the outbox events in one transaction,
+// which needs mongo to run as a replica set. Without events write runs on
+// its own.
+func (m *Mongo) withEvents(ctx context.Context, events []*entity.OutboxEvent, write func(ctx context.Context) error) error {
+	var err error
+	if len(events) == 0 {
+		err = write(ctx)
+	} else {
+		docs := make([]interface{}, 0, len(events))
+		for _, event := range events {
+			if event.Id, err = m.nextID(ctx, outboxEventsCollection); err != nil {
+				return err
+			}
+			docs = append(docs, event)
+		}
+
+		err = m.client.UseSession(ctx, func(sc mongo.SessionContext) error {
+			_, err := sc.WithTransaction(sc, func(tx mongo.SessionContext) (interface{}, error) {
+				if err := write(tx); err != nil {
+					return nil, err
+				}
+				return m.DB.Collection(outboxEventsCollection).InsertMany(tx, docs)
+			})
+			return err
+		})
+	}
+
+	if mongo.IsDuplicateKeyError(err) {
+		return drivers.ErrUserAlreadyExists
+	}
+	return err
+}
+
+func (m *Mongo) updateUser(ctx context.Context, filter, update bson.M) error {
+	res, err := m.DB.Collection(usersCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrUserNotFound
+	}
+	return nil
+}
+
+// active hides soft-deleted users, a nil filter matches a missing field too.
+func active(filter bson.M) bson.M {
+	filter["deleted_at"] = nil
+	return filter
+}
+
+func (m *Mongo) findUser(ctx context.Context, filter bson.M) (*entity.User, error) {
+	user := new(entity.User)
+	err := m.DB.Collection(usersCollection).FindOne(ctx, filter).Decode(user)
+	switch {
+	case err == nil:
+		return user, nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return nil, drivers.ErrUserNotFound
+	default:
+		return nil, err
+	}
+}
+
+// nextID атомарно увеличивает счетчик коллекции и возвращает новый идентификатор,
+// так id остаются целыми числами как serial в postgres
+func (m *Mongo) nextID(ctx context.Context, name string) (int, error) {
+	var c counter
+	err := m.DB.Collection(countersCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": name},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&c)
+	if err != nil {
+		return 0, err
+	}
+	return c.Seq, nil
 }
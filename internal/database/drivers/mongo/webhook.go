@@ -0,0 +1,208 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const (
+	webhookEndpointsCollection  = "webhook_endpoints"
+	webhookDeliveriesCollection = "webhook_deliveries"
+)
+
+func (m *Mongo) GetWebhookEndpoints(ctx context.Context) ([]*entity.WebhookEndpoint, error) {
+	cursor, err := m.DB.Collection(webhookEndpointsCollection).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	endpoints := make([]*entity.WebhookEndpoint, 0)
+	if err = cursor.All(ctx, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (m *Mongo) GetWebhookEndpoint(ctx context.Context, id int) (*entity.WebhookEndpoint, error) {
+	endpoint := new(entity.WebhookEndpoint)
+	err := m.DB.Collection(webhookEndpointsCollection).FindOne(ctx, bson.M{"_id": id}).Decode(endpoint)
+	switch {
+	case err == nil:
+		return endpoint, nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return nil, drivers.ErrWebhookNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *Mongo) CreateWebhookEndpoint(ctx context.Context, endpoint *entity.WebhookEndpoint) (int, error) {
+	id, err := m.nextID(ctx, webhookEndpointsCollection)
+	if err != nil {
+		return 0, err
+	}
+
+	endpoint.Id = id
+	if _, err = m.DB.Collection(webhookEndpointsCollection).InsertOne(ctx, endpoint); err != nil {
+		return 0, err
+	}
+	return endpoint.Id, nil
+}
+
+func (m *Mongo) UpdateWebhookEndpoint(ctx context.Context, endpoint *entity.WebhookEndpoint) error {
+	res, err := m.DB.Collection(webhookEndpointsCollection).UpdateOne(ctx,
+		bson.M{"_id": endpoint.Id},
+		bson.M{"$set": bson.M{
+			"url":         endpoint.URL,
+			"description": endpoint.Description,
+			"event_types": endpoint.EventTypes,
+			"secret":      endpoint.Secret,
+			"enabled":     endpoint.Enabled,
+			"updated_at":  endpoint.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (m *Mongo) DeleteWebhookEndpoint(ctx context.Context, id int) error {
+	res, err := m.DB.Collection(webhookEndpointsCollection).DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return drivers.ErrWebhookNotFound
+	}
+
+	_, err = m.DB.Collection(webhookDeliveriesCollection).DeleteMany(ctx, bson.M{"endpoint_id": id})
+	return err
+}
+
+// CreateWebhookDeliveries relies on the unique endpoint and event index to
+// skip deliveries that exist already.
+func (m *Mongo) CreateWebhookDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		id, err := m.nextID(ctx, webhookDeliveriesCollection)
+		if err != nil {
+			return err
+		}
+		delivery.Id = id
+		docs = append(docs, delivery)
+	}
+
+	_, err := m.DB.Collection(webhookDeliveriesCollection).InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+func (m *Mongo) GetWebhookDeliveries(ctx context.Context, query *drivers.WebhookDeliveryQuery) ([]*entity.WebhookDelivery, int64, error) {
+	filter := bson.M{}
+	if query.EndpointID != 0 {
+		filter["endpoint_id"] = query.EndpointID
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+
+	collection := m.DB.Collection(webhookDeliveriesCollection)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(query.Offset)).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := make([]*entity.WebhookDelivery, 0)
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+func (m *Mongo) GetWebhookDelivery(ctx context.Context, id int) (*entity.WebhookDelivery, error) {
+	delivery := new(entity.WebhookDelivery)
+	err := m.DB.Collection(webhookDeliveriesCollection).FindOne(ctx, bson.M{"_id": id}).Decode(delivery)
+	switch {
+	case err == nil:
+		return delivery, nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return nil, drivers.ErrDeliveryNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *Mongo) ClaimWebhookDeliveries(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*entity.WebhookDelivery, error) {
+	collection := m.DB.Collection(webhookDeliveriesCollection)
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"_id": 1}).
+		SetReturnDocument(options.After)
+
+	deliveries := make([]*entity.WebhookDelivery, 0, limit)
+	for len(deliveries) < limit {
+		delivery := new(entity.WebhookDelivery)
+		err := collection.FindOneAndUpdate(ctx,
+			bson.M{"status": entity.DeliveryPending, "next_attempt_at": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"next_attempt_at": lockedUntil}},
+			opts,
+		).Decode(delivery)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (m *Mongo) UpdateWebhookDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	set := bson.M{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"response_status": delivery.ResponseStatus,
+		"response_body":   delivery.ResponseBody,
+		"last_error":      delivery.LastError,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"delivered_at":    delivery.DeliveredAt,
+	}
+
+	res, err := m.DB.Collection(webhookDeliveriesCollection).UpdateOne(ctx, bson.M{"_id": delivery.Id}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return drivers.ErrDeliveryNotFound
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"context"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+)
+
+func (ur *Postgres) CreateAuditEvent(ctx context.Context, event *entity.AuditEvent) error {
+	return ur.client.WithContext(ctx).Create(event).Error
+}
+
+func (ur *Postgres) GetAuditEvents(ctx context.Context, query *drivers.AuditQuery) (events []*entity.AuditEvent, total int64, err error) {
+	db := ur.client.WithContext(ctx).Model(&entity.AuditEvent{})
+
+	if query.Type != "" {
+		db = db.Where("type = ?", query.Type)
+	}
+	if query.ActorID != 0 {
+		db = db.Where("actor_id = ?", query.ActorID)
+	}
+	if query.TargetID != 0 {
+		db = db.Where("target_id = ?", query.TargetID)
+	}
+	if query.IP != "" {
+		db = db.Where("ip = ?", query.IP)
+	}
+	if !query.From.IsZero() {
+		db = db.Where("created_at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		db = db.Where("created_at < ?", query.To)
+	}
+
+	if res := db.Count(&total); res.Error != nil {
+		return nil, 0, res.Error
+	}
+
+	res := db.Order("created_at desc, id desc").Limit(query.Limit).Offset(query.Offset).Find(&events)
+	if res.Error != nil {
+		return nil, 0, res.Error
+	}
+	return events, total, nil
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgconn"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"gorm.io/gorm"
+)
+
+func (ur *Postgres) GetClients(ctx context.Context) (clients []*entity.Client, err error) {
+	res := ur.client.WithContext(ctx).Order("id").Find(&clients)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return clients, nil
+}
+
+func (ur *Postgres) GetClientByClientID(ctx context.Context, clientID string) (client *entity.Client, err error) {
+	res := ur.client.WithContext(ctx).Where("client_id = ?", clientID).First(&client)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return nil, drivers.ErrClientNotFound
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return client, nil
+}
+
+func (ur *Postgres) CreateClient(ctx context.Context, client *entity.Client) (int, error) {
+	res := ur.client.WithContext(ctx).Create(client)
+	if res.Error != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(res.Error, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return 0, drivers.ErrClientAlreadyExists
+		}
+		return 0, res.Error
+	}
+	return client.Id, nil
+}
+
+func (ur *Postgres) UpdateClient(ctx context.Context, client *entity.Client) error {
+	res := ur.client.WithContext(ctx).Model(client).Where("client_id = ?", client.ClientID).
+		Select("secret_hash", "name", "redirect_uris", "scopes", "grant_types").Updates(client)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrClientNotFound
+	}
+	return nil
+}
+
+func (ur *Postgres) DeleteClient(ctx context.Context, clientID string) error {
+	res := ur.client.WithContext(ctx).Where("client_id = ?", clientID).Delete(&entity.Client{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrClientNotFound
+	}
+	return nil
+}
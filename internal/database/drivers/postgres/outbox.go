@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+// claimOutboxEventsQuery locks the due events with skip locked, so that relays
+// running side by side claim disjoint batches.
+const claimOutboxEventsQuery = `update outbox_events set next_attempt_at = ?
+where id in (
+    select id from outbox_events
+    where published_at is null and next_attempt_at <= ?
+    order by id
+    limit ?
+    for update skip locked
+)
+returning *`
+
+func (ur *Postgres) ClaimOutboxEvents(ctx context.Context, now, lockedUntil time.Time, limit int) (events []*entity.OutboxEvent, err error) {
+	res := ur.client.WithContext(ctx).Raw(claimOutboxEventsQuery, lockedUntil, now, limit).Scan(&events)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	// returning does not keep the order of the subquery
+	sort.Slice(events, func(i, j int) bool { return events[i].Id < events[j].Id })
+
+	return events, nil
+}
+
+func (ur *Postgres) MarkOutboxEventPublished(ctx context.Context, id int, publishedAt time.Time) error {
+	res := ur.client.WithContext(ctx).Model(&entity.OutboxEvent{Id: id}).Select("published_at").
+		Updates(&entity.OutboxEvent{PublishedAt: &publishedAt})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrOutboxEventNotFound
+	}
+	return nil
+}
+
+func (ur *Postgres) RetryOutboxEvent(ctx context.Context, id int, lastError string, nextAttemptAt time.Time) error {
+	res := ur.client.WithContext(ctx).Model(&entity.OutboxEvent{Id: id}).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      lastError,
+		"next_attempt_at": nextAttemptAt,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrOutboxEventNotFound
+	}
+	return nil
+}
+
+func (ur *Postgres) DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	res := ur.client.WithContext(ctx).Where("published_at < ?", before).Delete(&entity.OutboxEvent{})
+	return res.RowsAffected, res.Error
+}
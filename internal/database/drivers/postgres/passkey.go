@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgconn"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"gorm.io/gorm"
+)
+
+func (ur *Postgres) GetPasskeys(ctx context.Context, userID int) (passkeys []*entity.Passkey, err error) {
+	res := ur.client.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&passkeys)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return passkeys, nil
+}
+
+func (ur *Postgres) GetPasskeyByCredentialID(ctx context.Context, credentialID string) (passkey *entity.Passkey, err error) {
+	res := ur.client.WithContext(ctx).Where("credential_id = ?", credentialID).First(&passkey)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return nil, drivers.ErrPasskeyNotFound
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return passkey, nil
+}
+
+func (ur *Postgres) CreatePasskey(ctx context.Context, passkey *entity.Passkey) (int, error) {
+	res := ur.client.WithContext(ctx).Create(passkey)
+	if res.Error != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(res.Error, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return 0, drivers.ErrPasskeyAlreadyExists
+		}
+		return 0, res.Error
+	}
+	return passkey.Id, nil
+}
+
+func (ur *Postgres) UpdatePasskeyUsage(ctx context.Context, passkey *entity.Passkey) error {
+	res := ur.client.WithContext(ctx).Model(passkey).Where("credential_id = ?", passkey.CredentialID).
+		Select("sign_count", "backup_state", "last_used_at").Updates(passkey)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrPasskeyNotFound
+	}
+	return nil
+}
+
+func (ur *Postgres) DeletePasskey(ctx context.Context, userID int, credentialID string) error {
+	res := ur.client.WithContext(ctx).Where("user_id = ? and credential_id = ?", userID, credentialID).Delete(&entity.Passkey{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrPasskeyNotFound
+	}
+	return nil
+}
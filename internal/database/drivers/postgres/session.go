@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+func (ur *Postgres) GetSessions(ctx context.Context, userID int) (sessions []*entity.Session, err error) {
+	res := ur.client.WithContext(ctx).
+		Where("user_id = ? and revoked_at is null and expires_at > ?", userID, time.Now()).
+		Order("last_seen_at desc").Find(&sessions)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return sessions, nil
+}
+
+func (ur *Postgres) GetSession(ctx context.Context, id string) (session *entity.Session, err error) {
+	res := ur.client.WithContext(ctx).Where("id = ?", id).First(&session)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return nil, drivers.ErrSessionNotFound
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return session, nil
+}
+
+func (ur *Postgres) CreateSession(ctx context.Context, session *entity.Session) error {
+	return ur.client.WithContext(ctx).Create(session).Error
+}
+
+func (ur *Postgres) TouchSession(ctx context.Context, id string, lastSeenAt, expiresAt time.Time) error {
+	res := ur.client.WithContext(ctx).Model(&entity.Session{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_seen_at": lastSeenAt, "expires_at": expiresAt})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (ur *Postgres) RevokeSession(ctx context.Context, id string, revokedAt time.Time) error {
+	res := ur.client.WithContext(ctx).Model(&entity.Session{}).Where("id = ? and revoked_at is null", id).
+		Update("revoked_at", revokedAt)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (ur *Postgres) RevokeUserSessions(ctx context.Context, userID int, revokedAt time.Time) error {
+	return ur.client.WithContext(ctx).Model(&entity.Session{}).Where("user_id = ? and revoked_at is null", userID).
+		Update("revoked_at", revokedAt).Error
+}
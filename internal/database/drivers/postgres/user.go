@@ -2,29 +2,96 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
 	"github.com/madyar997/sso-jcode/internal/entity"
 	"github.com/opentracing/opentracing-go"
+	"gorm.io/gorm"
+	"strings"
+	"time"
 )
 
-type SearchQuery struct {
-	Limit     int
-	Offset    int
-	SortBy    string
-	SortOrder string
+const uniqueViolationCode = "23505"
+
+// userSortColumns maps the sort fields onto the expressions ordered by. The
+// nullable columns are coalesced, a null would break the keyset comparison.
+var userSortColumns = map[string]string{
+	"id":         "id",
+	"name":       "coalesce(name, '')",
+	"email":      "coalesce(email, '')",
+	"age":        "coalesce(age, 0)",
+	"created_at": "created_at",
 }
 
-func (ur *Postgres) GetUsers(ctx context.Context) (users []*entity.User, err error) {
-	res := ur.client.WithContext(ctx).Find(&users)
+func (ur *Postgres) GetUsers(ctx context.Context, query *drivers.SearchQuery) (users []*entity.User, total int64, err error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "get users - repo")
+	defer span.Finish()
+
+	column, ok := userSortColumns[query.SortBy]
+	if !ok {
+		return nil, 0, fmt.Errorf("users cannot be sorted by %q", query.SortBy)
+	}
+
+	db := ur.client.WithContext(ctx).Model(&entity.User{}).Scopes(active)
+
+	if query.Email != "" {
+		db = db.Where("email ilike ?", substring(query.Email))
+	}
+	if query.Name != "" {
+		db = db.Where("name ilike ?", substring(query.Name))
+	}
+	if query.MinAge != nil {
+		db = db.Where("age >= ?", *query.MinAge)
+	}
+	if query.MaxAge != nil {
+		db = db.Where("age <= ?", *query.MaxAge)
+	}
+	if !query.CreatedFrom.IsZero() {
+		db = db.Where("created_at >= ?", query.CreatedFrom)
+	}
+	if !query.CreatedTo.IsZero() {
+		db = db.Where("created_at < ?", query.CreatedTo)
+	}
+
+	if res := db.Count(&total); res.Error != nil {
+		return nil, 0, res.Error
+	}
+
+	direction, comparison := "asc", ">"
+	if query.SortOrder == drivers.SortDesc {
+		direction, comparison = "desc", "<"
+	}
+
+	if query.AfterID != 0 {
+		db = db.Where(fmt.Sprintf("(%[1]s %[2]s ? or (%[1]s = ? and id %[2]s ?))", column, comparison),
+			query.AfterValue, query.AfterValue, query.AfterID)
+	} else {
+		db = db.Offset(query.Offset)
+	}
+
+	res := db.Order(column + " " + direction + ", id " + direction).Limit(query.Limit).Find(&users)
 	if res.Error != nil {
-		return nil, res.Error
+		return nil, 0, res.Error
 	}
-	return users, nil
+	return users, total, nil
 }
 
-func (ur *Postgres) CreateUser(ctx context.Context, user *entity.User) (int, error) {
-	res := ur.client.WithContext(ctx).Create(user)
-	if res.Error != nil {
-		return 0, res.Error
+func (ur *Postgres) CreateUser(ctx context.Context, user *entity.User, events ...*entity.OutboxEvent) (int, error) {
+	err := ur.withEvents(ctx, events, func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			event.AggregateID = user.Id
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
 	return user.Id, nil
 }
@@ -33,9 +100,9 @@ func (ur *Postgres) GetUserByEmail(ctx context.Context, email string) (user *ent
 	span, _ := opentracing.StartSpanFromContext(ctx, "get user by email repo")
 	defer span.Finish()
 
-	res := ur.client.Where("email = ?", email).WithContext(ctx).Find(&user)
+	res := ur.client.Where("email = ?", email).WithContext(ctx).Scopes(active).First(&user)
 	if res.Error != nil {
-		return nil, res.Error
+		return nil, notFound(res.Error)
 	}
 	return user, nil
 }
@@ -44,9 +111,213 @@ func (ur *Postgres) GetUserByID(ctx context.Context, id int) (user *entity.User,
 	span, _ := opentracing.StartSpanFromContext(ctx, "get user by id - repo")
 	defer span.Finish()
 
-	res := ur.client.WithContext(ctx).Where("id = ?", id).Find(&user)
+	res := ur.client.WithContext(ctx).Where("id = ?", id).Scopes(active).First(&user)
 	if res.Error != nil {
-		return nil, res.Error
+		return nil, notFound(res.Error)
 	}
 	return user, nil
 }
+
+func (ur *Postgres) GetAnyUserByID(ctx context.Context, id int) (user *entity.User, err error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "get any user by id - repo")
+	defer span.Finish()
+
+	res := ur.client.WithContext(ctx).Where("id = ?", id).First(&user)
+	if res.Error != nil {
+		return nil, notFound(res.Error)
+	}
+	return user, nil
+}
+
+func (ur *Postgres) UpdateUserRoles(ctx context.Context, id int, roles, permissions []string) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "update user roles - repo")
+	defer span.Finish()
+
+	res := ur.client.WithContext(ctx).Model(&entity.User{Id: id}).Scopes(active).Select("roles", "permissions").
+		Updates(&entity.User{Roles: roles, Permissions: permissions})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrUserNotFound
+	}
+	return nil
+}
+
+func (ur *Postgres) UpdateUserMFA(ctx context.Context, id int, totpSecret string, enabled bool, recoveryCodes []string) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "update user mfa - repo")
+	defer span.Finish()
+
+	res := ur.client.WithContext(ctx).Model(&entity.User{Id: id}).Scopes(active).Select("totp_secret", "mfa_enabled", "recovery_codes").
+		Updates(&entity.User{TOTPSecret: totpSecret, MFAEnabled: enabled, RecoveryCodes: recoveryCodes})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrUserNotFound
+	}
+	return nil
+}
+
+func (ur *Postgres) RemoveRecoveryCode(ctx context.Context, id int, codeHash string) (bool, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "remove recovery code - repo")
+	defer span.Finish()
+
+	held, err := json.Marshal([]string{codeHash})
+	if err != nil {
+		return false, err
+	}
+
+	res := ur.client.WithContext(ctx).Model(&entity.User{Id: id}).Scopes(active).
+		Where("recovery_codes @> ?::jsonb", string(held)).
+		Update("recovery_codes", gorm.Expr("recovery_codes - ?::text", codeHash))
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (ur *Postgres) UpdateUserEmailVerified(ctx context.Context, id int, verified bool) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "update user email verified - repo")
+	defer span.Finish()
+
+	res := ur.client.WithContext(ctx).Model(&entity.User{Id: id}).Scopes(active).Select("email_verified").
+		Updates(&entity.User{EmailVerified: verified})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrUserNotFound
+	}
+	return nil
+}
+
+func (ur *Postgres) UpdateUserPassword(ctx context.Context, id int, passwordHash string, history []string) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "update user password - repo")
+	defer span.Finish()
+
+	res := ur.client.WithContext(ctx).Model(&entity.User{Id: id}).Scopes(active).Select("password", "password_history").
+		Updates(&entity.User{Password: passwordHash, PasswordHistory: history})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrUserNotFound
+	}
+	return nil
+}
+
+func (ur *Postgres) UpdateUser(ctx context.Context, user *entity.User, events ...*entity.OutboxEvent) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "update user - repo")
+	defer span.Finish()
+
+	return ur.withEvents(ctx, events, func(tx *gorm.DB) error {
+		return updated(tx.Model(&entity.User{Id: user.Id}).Scopes(active).
+			Select("name", "email", "age", "email_verified").
+			Updates(&entity.User{Name: user.Name, Email: user.Email, Age: user.Age, EmailVerified: user.EmailVerified}))
+	})
+}
+
+func (ur *Postgres) PatchUser(ctx context.Context, id int, patch *entity.UserPatch, events ...*entity.OutboxEvent) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "patch user - repo")
+	defer span.Finish()
+
+	fields := make(map[string]interface{})
+	if patch.Name != nil {
+		fields["name"] = *patch.Name
+	}
+	if patch.Email != nil {
+		fields["email"] = *patch.Email
+	}
+	if patch.Age != nil {
+		fields["age"] = *patch.Age
+	}
+	if patch.EmailVerified != nil {
+		fields["email_verified"] = *patch.EmailVerified
+	}
+
+	return ur.withEvents(ctx, events, func(tx *gorm.DB) error {
+		if len(fields) == 0 {
+			return notFound(tx.Scopes(active).Select("id").First(&entity.User{}, id).Error)
+		}
+		return updated(tx.Model(&entity.User{Id: id}).Scopes(active).Updates(fields))
+	})
+}
+
+func (ur *Postgres) DeleteUser(ctx context.Context, id int, deletedAt time.Time, events ...*entity.OutboxEvent) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "delete user - repo")
+	defer span.Finish()
+
+	return ur.withEvents(ctx, events, func(tx *gorm.DB) error {
+		return updated(tx.Model(&entity.User{Id: id}).Scopes(active).Update("deleted_at", deletedAt))
+	})
+}
+
+func (ur *Postgres) RestoreUser(ctx context.Context, id int, events ...*entity.OutboxEvent) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "restore user - repo")
+	defer span.Finish()
+
+	return ur.withEvents(ctx, events, func(tx *gorm.DB) error {
+		return updated(tx.Model(&entity.User{Id: id}).Where("deleted_at is not null").Update("deleted_at", nil))
+	})
+}
+
+// HardDeleteUser relies on the foreign keys to drop the sessions and passkeys.
+func (ur *Postgres) HardDeleteUser(ctx context.Context, id int, events ...*entity.OutboxEvent) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "hard delete user - repo")
+	defer span.Finish()
+
+	return ur.withEvents(ctx, events, func(tx *gorm.DB) error {
+		return updated(tx.Delete(&entity.User{}, id))
+	})
+}
+
+// withEvents runs write and stores the outbox events in one transaction.
+func (ur *Postgres) withEvents(ctx context.Context, events []*entity.OutboxEvent, write func(tx *gorm.DB) error) error {
+	err := ur.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := write(tx); err != nil {
+			return err
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+		return tx.Create(events).Error
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return drivers.ErrUserAlreadyExists
+	}
+	return err
+}
+
+// substring builds an ilike pattern matching value anywhere, its own
+// wildcards are matched literally.
+func substring(value string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value) + "%"
+}
+
+// active hides soft-deleted users from a query.
+func active(db *gorm.DB) *gorm.DB {
+	return db.Where("deleted_at is null")
+}
+
+// updated reports ErrUserNotFound when a write matched no user.
+func updated(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrUserNotFound
+	}
+	return nil
+}
+
+// notFound maps gorm's missing-row error onto the driver-agnostic one.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return drivers.ErrUserNotFound
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"time"
+)
+
+const claimWebhookDeliveriesQuery = `update webhook_deliveries set next_attempt_at = ?
+where id in (
+    select id from webhook_deliveries
+    where status = 'pending' and next_attempt_at <= ?
+    order by id
+    limit ?
+    for update skip locked
+)
+returning *`
+
+func (ur *Postgres) GetWebhookEndpoints(ctx context.Context) (endpoints []*entity.WebhookEndpoint, err error) {
+	res := ur.client.WithContext(ctx).Order("id").Find(&endpoints)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return endpoints, nil
+}
+
+func (ur *Postgres) GetWebhookEndpoint(ctx context.Context, id int) (endpoint *entity.WebhookEndpoint, err error) {
+	res := ur.client.WithContext(ctx).Where("id = ?", id).First(&endpoint)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return nil, drivers.ErrWebhookNotFound
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return endpoint, nil
+}
+
+func (ur *Postgres) CreateWebhookEndpoint(ctx context.Context, endpoint *entity.WebhookEndpoint) (int, error) {
+	res := ur.client.WithContext(ctx).Create(endpoint)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	return endpoint.Id, nil
+}
+
+func (ur *Postgres) UpdateWebhookEndpoint(ctx context.Context, endpoint *entity.WebhookEndpoint) error {
+	res := ur.client.WithContext(ctx).Model(endpoint).
+		Select("url", "description", "event_types", "secret", "enabled", "updated_at").Updates(endpoint)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (ur *Postgres) DeleteWebhookEndpoint(ctx context.Context, id int) error {
+	// deliveries go with it by the foreign key
+	res := ur.client.WithContext(ctx).Where("id = ?", id).Delete(&entity.WebhookEndpoint{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (ur *Postgres) CreateWebhookDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return ur.client.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(deliveries).Error
+}
+
+func (ur *Postgres) GetWebhookDeliveries(ctx context.Context, query *drivers.WebhookDeliveryQuery) (deliveries []*entity.WebhookDelivery, total int64, err error) {
+	db := ur.client.WithContext(ctx).Model(&entity.WebhookDelivery{})
+
+	if query.EndpointID != 0 {
+		db = db.Where("endpoint_id = ?", query.EndpointID)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+
+	if res := db.Count(&total); res.Error != nil {
+		return nil, 0, res.Error
+	}
+
+	res := db.Order("created_at desc, id desc").Limit(query.Limit).Offset(query.Offset).Find(&deliveries)
+	if res.Error != nil {
+		return nil, 0, res.Error
+	}
+	return deliveries, total, nil
+}
+
+func (ur *Postgres) GetWebhookDelivery(ctx context.Context, id int) (delivery *entity.WebhookDelivery, err error) {
+	res := ur.client.WithContext(ctx).Where("id = ?", id).First(&delivery)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return nil, drivers.ErrDeliveryNotFound
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return delivery, nil
+}
+
+func (ur *Postgres) ClaimWebhookDeliveries(ctx context.Context, now, lockedUntil time.Time, limit int) (deliveries []*entity.WebhookDelivery, err error) {
+	res := ur.client.WithContext(ctx).Raw(claimWebhookDeliveriesQuery, lockedUntil, now, limit).Scan(&deliveries)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	// returning does not keep the order of the subquery
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].Id < deliveries[j].Id })
+
+	return deliveries, nil
+}
+
+func (ur *Postgres) UpdateWebhookDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	res := ur.client.WithContext(ctx).Model(delivery).
+		Select("status", "attempts", "response_status", "response_body", "last_error", "next_attempt_at", "delivered_at").
+		Updates(delivery)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return drivers.ErrDeliveryNotFound
+	}
+	return nil
+}
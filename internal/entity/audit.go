@@ -0,0 +1,37 @@
+package entity
+
+import "time"
+
+// Audit event types.
+const (
+	AuditLoginSucceeded  = "login.succeeded"
+	AuditLoginFailed     = "login.failed"
+	AuditUserRegistered  = "user.registered"
+	AuditUserCreated     = "user.created"
+	AuditRolesChanged    = "user.roles_changed"
+	AuditUserUpdated     = "user.updated"
+	AuditUserDeleted     = "user.deleted"
+	AuditUserRestored    = "user.restored"
+	AuditUserPurged      = "user.purged"
+	AuditTokenRefreshed  = "token.refreshed"
+	AuditTokenReused     = "token.reused"
+	AuditPasswordChanged = "password.changed"
+	AuditPasswordReset   = "password.reset"
+)
+
+// AuditEvent is an entry of the append-only audit trail. The actor did
+// something to the target, both are zero when unknown; for logins and
+// self-service they are the same user.
+type AuditEvent struct {
+	Id          int               `json:"id" bson:"_id"`
+	Type        string            `json:"type" bson:"type"`
+	ActorID     int               `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	ActorEmail  string            `json:"actor_email,omitempty" bson:"actor_email,omitempty"`
+	TargetID    int               `json:"target_id,omitempty" bson:"target_id,omitempty"`
+	TargetEmail string            `json:"target_email,omitempty" bson:"target_email,omitempty"`
+	IP          string            `json:"ip,omitempty" bson:"ip,omitempty" gorm:"column:ip"`
+	UserAgent   string            `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	TraceID     string            `json:"trace_id,omitempty" bson:"trace_id,omitempty"`
+	Details     map[string]string `json:"details,omitempty" bson:"details,omitempty" gorm:"serializer:json"`
+	CreatedAt   time.Time         `json:"created_at" bson:"created_at"`
+}
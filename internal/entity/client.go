@@ -0,0 +1,37 @@
+package entity
+
+import "time"
+
+// Client is an application registered with the SSO. Clients without a secret
+// are public (browser and mobile apps) and must use PKCE.
+type Client struct {
+	Id           int       `json:"id" bson:"_id"`
+	ClientID     string    `json:"client_id" bson:"client_id"`
+	SecretHash   string    `json:"-" bson:"secret_hash"`
+	Name         string    `json:"name" bson:"name"`
+	RedirectURIs []string  `json:"redirect_uris" gorm:"column:redirect_uris;serializer:json" bson:"redirect_uris"`
+	Scopes       []string  `json:"scopes" gorm:"serializer:json" bson:"scopes"`
+	GrantTypes   []string  `json:"grant_types" gorm:"serializer:json" bson:"grant_types"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+}
+
+func (c *Client) Public() bool {
+	return c.SecretHash == ""
+}
+
+func (c *Client) AllowsGrant(grantType string) bool {
+	return contains(c.GrantTypes, grantType)
+}
+
+func (c *Client) AllowsRedirectURI(uri string) bool {
+	return contains(c.RedirectURIs, uri)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// Domain event types published through the outbox.
+const (
+	EventUserRegistered   = "user.registered"
+	EventUserCreated      = "user.created"
+	EventUserEmailChanged = "user.email_changed"
+	EventUserDeleted      = "user.deleted"
+	EventUserRestored     = "user.restored"
+)
+
+// OutboxEvent is a domain event stored in the same transaction as the change
+// it describes and published by the relay afterwards. AggregateID is the id of
+// the user the event is about.
+type OutboxEvent struct {
+	Id          int                    `json:"id" bson:"_id"`
+	Type        string                 `json:"type" bson:"type"`
+	AggregateID int                    `json:"aggregate_id" bson:"aggregate_id"`
+	Payload     map[string]interface{} `json:"payload" bson:"payload" gorm:"serializer:json"`
+	// Attempts counts the failed publish attempts, NextAttemptAt is when the
+	// relay may pick the event up again.
+	Attempts      int        `json:"attempts" bson:"attempts"`
+	LastError     string     `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" bson:"next_attempt_at"`
+	PublishedAt   *time.Time `json:"published_at,omitempty" bson:"published_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// Passkey is a WebAuthn credential registered by a user. CredentialID is the
+// base64url encoded raw credential id.
+type Passkey struct {
+	Id              int        `json:"id" bson:"_id"`
+	UserID          int        `json:"user_id" bson:"user_id"`
+	CredentialID    string     `json:"credential_id" bson:"credential_id"`
+	Name            string     `json:"name" bson:"name"`
+	PublicKey       []byte     `json:"-" bson:"public_key"`
+	AttestationType string     `json:"-" bson:"attestation_type"`
+	Transports      []string   `json:"transports" gorm:"serializer:json" bson:"transports"`
+	AAGUID          []byte     `json:"-" gorm:"column:aaguid" bson:"aaguid"`
+	SignCount       uint32     `json:"-" bson:"sign_count"`
+	BackupEligible  bool       `json:"backup_eligible" bson:"backup_eligible"`
+	BackupState     bool       `json:"backup_state" bson:"backup_state"`
+	CreatedAt       time.Time  `json:"created_at" bson:"created_at"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+}
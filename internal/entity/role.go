@@ -0,0 +1,55 @@
+package entity
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+const (
+	PermissionUsersRead     = "users:read"
+	PermissionUsersWrite    = "users:write"
+	PermissionClientsManage = "clients:manage"
+	PermissionKeysRotate    = "keys:rotate"
+	// PermissionTokensIntrospect lets resource servers validate tokens over gRPC.
+	PermissionTokensIntrospect = "tokens:introspect"
+)
+
+// RolePermissions lists the permissions every role grants. Permissions set
+// directly on a user are added on top.
+var RolePermissions = map[string][]string{
+	RoleAdmin: {PermissionUsersRead, PermissionUsersWrite, PermissionClientsManage, PermissionKeysRotate,
+		PermissionTokensIntrospect},
+	RoleUser: {},
+}
+
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectivePermissions returns the permissions granted by the user's roles
+// together with the ones assigned directly, without duplicates.
+func (u *User) EffectivePermissions() []string {
+	seen := make(map[string]bool)
+	permissions := make([]string, 0)
+
+	add := func(values []string) {
+		for _, p := range values {
+			if !seen[p] {
+				seen[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+
+	for _, role := range u.Roles {
+		add(RolePermissions[role])
+	}
+	add(u.Permissions)
+
+	return permissions
+}
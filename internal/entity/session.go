@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// Session is one login of a user. Its id is the id of the refresh token
+// family started by the login, access tokens carry it as sid.
+type Session struct {
+	ID         string     `json:"id" bson:"_id" gorm:"primaryKey"`
+	UserID     int        `json:"user_id" bson:"user_id"`
+	ClientID   string     `json:"client_id,omitempty" bson:"client_id,omitempty"`
+	Device     string     `json:"device" bson:"device"`
+	UserAgent  string     `json:"user_agent" bson:"user_agent"`
+	IP         string     `json:"ip" bson:"ip" gorm:"column:ip"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" bson:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at" bson:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// Origin describes where a login comes from.
+type Origin struct {
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
@@ -0,0 +1,45 @@
+package entity
+
+import "time"
+
+// RefreshToken is the server side record of an opaque refresh token. Only the
+// hash of the token is stored, FamilyID links all tokens rotated from one login.
+// Scope, ClientID and AuthTime describe that login and are kept on rotation.
+type RefreshToken struct {
+	UserID    int       `json:"user_id"`
+	FamilyID  string    `json:"family_id"`
+	ClientID  string    `json:"client_id,omitempty"`
+	Scope     string    `json:"scope,omitempty"`
+	AuthTime  time.Time `json:"auth_time"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AuthorizationCode is a one-time code issued by the authorize endpoint and
+// bound to the client, redirect uri and PKCE challenge of the request.
+type AuthorizationCode struct {
+	ClientID      string    `json:"client_id"`
+	RedirectURI   string    `json:"redirect_uri"`
+	UserID        int       `json:"user_id"`
+	Scope         string    `json:"scope"`
+	Nonce         string    `json:"nonce,omitempty"`
+	AuthTime      time.Time `json:"auth_time"`
+	CodeChallenge string    `json:"code_challenge"`
+	// Origin is where the user authorized from.
+	Origin Origin `json:"origin"`
+	// ExplicitURI is set when the authorization request carried the
+	// redirect_uri, the token request must then repeat it (RFC 6749 4.1.3).
+	ExplicitURI bool `json:"explicit_uri,omitempty"`
+}
+
+// MFAChallenge is the state between a password check and the second factor.
+type MFAChallenge struct {
+	UserID   int       `json:"user_id"`
+	AuthTime time.Time `json:"auth_time"`
+}
+
+// PasswordReset is the state behind an emailed password reset token, stored
+// under the hash of the token. Email pins it to the address it was sent to.
+type PasswordReset struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+}
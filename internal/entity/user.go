@@ -2,14 +2,39 @@ package entity
 
 import (
 	"github.com/golang-jwt/jwt"
+	"time"
 )
 
 type User struct {
-	Id       int    `json:"id"`
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Age      int    `json:"age"`
-	Password string `json:"password"`
+	Id          int      `json:"id" bson:"_id"`
+	Name        string   `json:"name" bson:"name"`
+	Email       string   `json:"email" bson:"email"`
+	Age         int      `json:"age" bson:"age"`
+	Password    string   `json:"password" bson:"password"`
+	Roles       []string `json:"roles" bson:"roles" gorm:"serializer:json"`
+	Permissions []string `json:"permissions,omitempty" bson:"permissions,omitempty" gorm:"serializer:json"`
+	// TOTPSecret is set on enrollment, MFAEnabled only after the first code
+	// was confirmed. RecoveryCodes holds sha256 hashes of unused codes.
+	TOTPSecret    string   `json:"-" bson:"totp_secret,omitempty" gorm:"column:totp_secret"`
+	MFAEnabled    bool     `json:"mfa_enabled" bson:"mfa_enabled" gorm:"column:mfa_enabled"`
+	RecoveryCodes []string `json:"-" bson:"recovery_codes,omitempty" gorm:"serializer:json"`
+	// PasswordHistory keeps the hashes of previous passwords, newest first.
+	PasswordHistory []string `json:"-" bson:"password_history,omitempty" gorm:"serializer:json"`
+	// EmailVerified is set once the user opened the verification link.
+	EmailVerified bool      `json:"email_verified" bson:"email_verified" gorm:"column:email_verified"`
+	CreatedAt     time.Time `json:"created_at" bson:"created_at"`
+	// DeletedAt is set while the account is soft-deleted, such users are
+	// hidden from lookups and cannot log in until restored.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+}
+
+// UserPatch holds the profile fields of a partial update, nil fields are
+// left as they are.
+type UserPatch struct {
+	Name          *string
+	Email         *string
+	Age           *int
+	EmailVerified *bool
 }
 
 type Token struct {
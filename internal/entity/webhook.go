@@ -0,0 +1,50 @@
+package entity
+
+import "time"
+
+// WebhookAllEvents subscribes an endpoint to every event type.
+const WebhookAllEvents = "*"
+
+// Webhook delivery states.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookEndpoint is a url registered by an integrator to receive the domain
+// events of the listed types. Requests to it are signed with Secret.
+type WebhookEndpoint struct {
+	Id          int       `json:"id" bson:"_id"`
+	URL         string    `json:"url" bson:"url" gorm:"column:url"`
+	Description string    `json:"description" bson:"description"`
+	EventTypes  []string  `json:"event_types" bson:"event_types" gorm:"serializer:json"`
+	Secret      string    `json:"-" bson:"secret"`
+	Enabled     bool      `json:"enabled" bson:"enabled"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+func (e *WebhookEndpoint) Subscribes(eventType string) bool {
+	return contains(e.EventTypes, WebhookAllEvents) || contains(e.EventTypes, eventType)
+}
+
+// WebhookDelivery is one event sent to one endpoint. Payload is the exact
+// request body, a replay sends the same bytes with a fresh signature.
+type WebhookDelivery struct {
+	Id         int    `json:"id" bson:"_id"`
+	EndpointID int    `json:"endpoint_id" bson:"endpoint_id"`
+	EventID    int    `json:"event_id" bson:"event_id"`
+	EventType  string `json:"event_type" bson:"event_type"`
+	Payload    string `json:"payload" bson:"payload"`
+	Status     string `json:"status" bson:"status"`
+	Attempts   int    `json:"attempts" bson:"attempts"`
+	// ResponseStatus and ResponseBody are from the last attempt, zero when
+	// the endpoint could not be reached.
+	ResponseStatus int        `json:"response_status,omitempty" bson:"response_status,omitempty"`
+	ResponseBody   string     `json:"response_body,omitempty" bson:"response_body,omitempty"`
+	LastError      string     `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" bson:"next_attempt_at"`
+	CreatedAt      time.Time  `json:"created_at" bson:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+}
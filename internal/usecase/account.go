@@ -0,0 +1,256 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// UpdateUser replaces the name, email and age of the user with the ones in
+// user. A changed email has to be verified again.
+func (u *User) UpdateUser(ctx context.Context, user *entity.User) (*entity.User, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "update user use case")
+	defer span.Finish()
+
+	current, err := u.repo.GetUserByID(spanCtx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	next := *current
+	next.Name, next.Email, next.Age = user.Name, strings.TrimSpace(user.Email), user.Age
+	if next.Email != current.Email {
+		next.EmailVerified = false
+	}
+
+	return u.saveProfile(spanCtx, current, &next, func(events []*entity.OutboxEvent) error {
+		return u.repo.UpdateUser(spanCtx, &next, events...)
+	})
+}
+
+// PatchUser changes the fields set in the patch. A changed email has to be
+// verified again unless the patch sets EmailVerified as well.
+func (u *User) PatchUser(ctx context.Context, id int, patch *entity.UserPatch) (*entity.User, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "patch user use case")
+	defer span.Finish()
+
+	current, err := u.repo.GetUserByID(spanCtx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := *patch
+	next := *current
+	if changes.Name != nil {
+		next.Name = *changes.Name
+	}
+	if changes.Email != nil {
+		email := strings.TrimSpace(*changes.Email)
+		changes.Email, next.Email = &email, email
+	}
+	if changes.Age != nil {
+		next.Age = *changes.Age
+	}
+	if changes.EmailVerified != nil {
+		next.EmailVerified = *changes.EmailVerified
+	} else if next.Email != current.Email {
+		unverified := false
+		changes.EmailVerified, next.EmailVerified = &unverified, false
+	}
+
+	return u.saveProfile(spanCtx, current, &next, func(events []*entity.OutboxEvent) error {
+		return u.repo.PatchUser(spanCtx, id, &changes, events...)
+	})
+}
+
+// saveProfile validates next, stores it with save and records what changed.
+// A new email is announced with a user.email_changed event in the same
+// transaction and gets a verification link.
+func (u *User) saveProfile(ctx context.Context, current, next *entity.User,
+	save func(events []*entity.OutboxEvent) error) (*entity.User, error) {
+	emailChanged := next.Email != current.Email
+
+	if err := validateProfile(next, emailChanged); err != nil {
+		return nil, err
+	}
+
+	fields := changedFields(current, next)
+	if len(fields) == 0 {
+		return next, nil
+	}
+
+	var events []*entity.OutboxEvent
+	details := map[string]string{"fields": strings.Join(fields, ",")}
+	if emailChanged {
+		event := userEvent(entity.EventUserEmailChanged, next)
+		event.Payload["previous_email"] = current.Email
+		events = append(events, event)
+		details["previous_email"] = current.Email
+	}
+
+	if err := save(events); err != nil {
+		return nil, err
+	}
+
+	u.evict(ctx, current.Email, next.Email)
+
+	u.logger.Info("user updated", zap.Int("user_id", next.Id), zap.Strings("fields", fields))
+
+	u.record(ctx, &entity.AuditEvent{
+		Type:        entity.AuditUserUpdated,
+		TargetID:    next.Id,
+		TargetEmail: next.Email,
+		Details:     details,
+	})
+
+	if emailChanged && !next.EmailVerified {
+		if err := u.sendVerification(ctx, next); err != nil {
+			u.logger.Error("could not send verification email", zap.Int("user_id", next.Id), zap.Error(err))
+		}
+	}
+
+	return next, nil
+}
+
+// DeleteUser soft-deletes the user and ends all of their sessions. The
+// account keeps its email and can be restored.
+func (u *User) DeleteUser(ctx context.Context, id int) error {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "delete user use case")
+	defer span.Finish()
+
+	user, err := u.repo.GetUserByID(spanCtx, id)
+	if err != nil {
+		return err
+	}
+
+	event := userEvent(entity.EventUserDeleted, user)
+	event.Payload["permanent"] = false
+
+	if err = u.repo.DeleteUser(spanCtx, id, time.Now(), event); err != nil {
+		return err
+	}
+
+	u.evict(spanCtx, user.Email)
+
+	u.logger.Info("user deleted", zap.Int("user_id", id))
+
+	u.record(spanCtx, &entity.AuditEvent{
+		Type:        entity.AuditUserDeleted,
+		TargetID:    id,
+		TargetEmail: user.Email,
+	})
+
+	return u.revokeUser(spanCtx, id)
+}
+
+// RestoreUser brings a soft-deleted user back, they log in again with their
+// old password.
+func (u *User) RestoreUser(ctx context.Context, id int) (*entity.User, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "restore user use case")
+	defer span.Finish()
+
+	user, err := u.repo.GetAnyUserByID(spanCtx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt == nil {
+		return nil, ErrUserNotDeleted
+	}
+
+	user.DeletedAt = nil
+
+	if err = u.repo.RestoreUser(spanCtx, id, userEvent(entity.EventUserRestored, user)); err != nil {
+		return nil, err
+	}
+
+	u.logger.Info("user restored", zap.Int("user_id", id))
+
+	u.record(spanCtx, &entity.AuditEvent{
+		Type:        entity.AuditUserRestored,
+		TargetID:    id,
+		TargetEmail: user.Email,
+	})
+
+	return user, nil
+}
+
+// HardDeleteUser removes the user for good, soft-deleted or not. The audit
+// trail keeps the events about the account.
+func (u *User) HardDeleteUser(ctx context.Context, id int) error {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "hard delete user use case")
+	defer span.Finish()
+
+	user, err := u.repo.GetAnyUserByID(spanCtx, id)
+	if err != nil {
+		return err
+	}
+
+	event := userEvent(entity.EventUserDeleted, user)
+	event.Payload["permanent"] = true
+
+	if err = u.repo.HardDeleteUser(spanCtx, id, event); err != nil {
+		return err
+	}
+
+	u.evict(spanCtx, user.Email)
+
+	u.logger.Info("user purged", zap.Int("user_id", id))
+
+	u.record(spanCtx, &entity.AuditEvent{
+		Type:        entity.AuditUserPurged,
+		TargetID:    id,
+		TargetEmail: user.Email,
+	})
+
+	if user.DeletedAt != nil {
+		return nil
+	}
+	return u.revokeUser(spanCtx, id)
+}
+
+// evict drops the user from the lookup cache of every transport. A failure
+// only leaves a stale entry until it expires.
+func (u *User) evict(ctx context.Context, emails ...string) {
+	if err := u.users.Delete(ctx, emails...); err != nil {
+		u.logger.Error("could not evict cached user", zap.Error(err))
+	}
+}
+
+// validateProfile checks the fields an admin may edit. The email is only
+// checked when it changes, older accounts were registered without a check.
+func validateProfile(user *entity.User, emailChanged bool) error {
+	if emailChanged {
+		addr, err := mail.ParseAddress(user.Email)
+		if err != nil || addr.Address != user.Email {
+			return fmt.Errorf("%w: email %q is not valid", ErrInvalidProfile, user.Email)
+		}
+	}
+
+	if user.Age < 0 {
+		return fmt.Errorf("%w: age must not be negative", ErrInvalidProfile)
+	}
+
+	return nil
+}
+
+func changedFields(current, next *entity.User) []string {
+	var fields []string
+	if current.Name != next.Name {
+		fields = append(fields, "name")
+	}
+	if current.Email != next.Email {
+		fields = append(fields, "email")
+	}
+	if current.Age != next.Age {
+		fields = append(fields, "age")
+	}
+	if current.EmailVerified != next.EmailVerified {
+		fields = append(fields, "email_verified")
+	}
+	return fields
+}
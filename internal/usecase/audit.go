@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+)
+
+const (
+	AuditPageSize    = 50
+	AuditMaxPageSize = 500
+)
+
+// reasons of failed logins in the audit trail
+const (
+	loginFailedThrottled        = "throttled"
+	loginFailedUnknownEmail     = "unknown_email"
+	loginFailedWrongPassword    = "wrong_password"
+	loginFailedEmailNotVerified = "email_not_verified"
+	loginFailedWrongMFACode     = "wrong_mfa_code"
+)
+
+type Audit struct {
+	repo drivers.AuditRepo
+}
+
+func NewAudit(repo drivers.AuditRepo) *Audit {
+	return &Audit{repo: repo}
+}
+
+// AuditEvents returns a page of the audit trail, newest first, and the number
+// of events matching the filters. The limit of req is set to the page size
+// used.
+func (a *Audit) AuditEvents(ctx context.Context, req *dto.AuditQueryRequest) ([]*entity.AuditEvent, int64, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "audit events use case")
+	defer span.Finish()
+
+	switch {
+	case req.Limit <= 0:
+		req.Limit = AuditPageSize
+	case req.Limit > AuditMaxPageSize:
+		req.Limit = AuditMaxPageSize
+	}
+
+	return a.repo.GetAuditEvents(spanCtx, &drivers.AuditQuery{
+		Type:     req.Type,
+		ActorID:  req.ActorID,
+		TargetID: req.TargetID,
+		IP:       req.IP,
+		From:     req.From,
+		To:       req.To,
+		Limit:    req.Limit,
+		Offset:   req.Offset,
+	})
+}
+
+// record appends an event to the audit trail. A failing sink is logged, it
+// does not fail the audited action.
+func (u *User) record(ctx context.Context, event *entity.AuditEvent) {
+	if err := u.auditor.Record(ctx, event); err != nil {
+		u.logger.Error("could not write audit event", zap.String("type", event.Type), zap.Error(err))
+	}
+}
+
+// selfEvent is an event of a user acting on their own account.
+func selfEvent(eventType string, user *entity.User, details map[string]string) *entity.AuditEvent {
+	return &entity.AuditEvent{
+		Type:        eventType,
+		ActorID:     user.Id,
+		ActorEmail:  user.Email,
+		TargetID:    user.Id,
+		TargetEmail: user.Email,
+		Details:     details,
+	}
+}
+
+// recordLoginFailed records a rejected login. The user is nil when the email
+// does not belong to an account.
+func (u *User) recordLoginFailed(ctx context.Context, email string, user *entity.User, reason string) {
+	event := &entity.AuditEvent{
+		Type:        entity.AuditLoginFailed,
+		TargetEmail: email,
+		Details:     map[string]string{"reason": reason},
+	}
+	if user != nil {
+		event.TargetID, event.TargetEmail = user.Id, user.Email
+	}
+
+	u.record(ctx, event)
+}
@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	LoginMaxFailures   = 5
+	LoginLockout       = 900
+	LoginIPMaxFailures = 20
+	LoginBackoff       = 1
+	LoginMaxBackoff    = 60
+	LoginFailureWindow = 900
+)
+
+const (
+	blockReasonBackoff = "backoff"
+	blockReasonLockout = "lockout"
+)
+
+// RetryAfterError is returned for logins rejected before the password was
+// checked. It wraps ErrAccountLocked or ErrTooManyAttempts.
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", e.Err, e.RetryAfter.Round(time.Second))
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// UnlockUser lifts the lockout of an account and forgets its failed logins.
+func (u *User) UnlockUser(ctx context.Context, id int) error {
+	user, err := u.repo.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	u.logger.Info("user unlocked", zap.Int("user_id", id))
+
+	return u.loginAttempts.Reset(ctx, emailAttemptsKey(user.Email))
+}
+
+// checkLoginAllowed rejects the attempt while the client ip or the email is
+// blocked. Unknown emails are counted like known ones, so a lockout does not
+// tell whether an account exists.
+func (u *User) checkLoginAllowed(ctx context.Context, email, clientIP string) error {
+	if clientIP != "" {
+		reason, remaining, err := u.loginAttempts.Blocked(ctx, ipAttemptsKey(clientIP))
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			return &RetryAfterError{Err: ErrTooManyAttempts, RetryAfter: remaining}
+		}
+	}
+
+	reason, remaining, err := u.loginAttempts.Blocked(ctx, emailAttemptsKey(email))
+	if err != nil {
+		return err
+	}
+
+	switch reason {
+	case "":
+		return nil
+	case blockReasonLockout:
+		return &RetryAfterError{Err: ErrAccountLocked, RetryAfter: remaining}
+	default:
+		return &RetryAfterError{Err: ErrTooManyAttempts, RetryAfter: remaining}
+	}
+}
+
+// loginFailed counts the failure for the email and the client ip. Every
+// failure delays the next attempt for the email exponentially until the
+// account is locked; the ip is only throttled once it passes its own limit.
+func (u *User) loginFailed(ctx context.Context, email, clientIP string) error {
+	cfg := u.cfg.BruteForce
+	window := ttl(cfg.Window, LoginFailureWindow)
+
+	emailKey := emailAttemptsKey(email)
+
+	failures, err := u.loginAttempts.RecordFailure(ctx, emailKey, window)
+	if err != nil {
+		return err
+	}
+
+	if failures >= int64(orDefault(cfg.MaxFailures, LoginMaxFailures)) {
+		u.logger.Warn("account locked after failed logins", zap.String("email", email), zap.Int64("failures", failures))
+
+		err = u.loginAttempts.Block(ctx, emailKey, blockReasonLockout, ttl(cfg.Lockout, LoginLockout))
+	} else {
+		err = u.loginAttempts.Block(ctx, emailKey, blockReasonBackoff, u.backoff(failures))
+	}
+	if err != nil {
+		return err
+	}
+
+	if clientIP == "" {
+		return nil
+	}
+
+	ipKey := ipAttemptsKey(clientIP)
+
+	failures, err = u.loginAttempts.RecordFailure(ctx, ipKey, window)
+	if err != nil {
+		return err
+	}
+
+	if over := failures - int64(orDefault(cfg.IPMaxFailures, LoginIPMaxFailures)); over >= 0 {
+		u.logger.Warn("client ip throttled after failed logins", zap.String("ip", clientIP), zap.Int64("failures", failures))
+
+		return u.loginAttempts.Block(ctx, ipKey, blockReasonBackoff, u.backoff(over+1))
+	}
+
+	return nil
+}
+
+// loginSucceeded forgets the failures of the email. The ip keeps its count,
+// one valid account must not clear the throttling of a guessing client.
+func (u *User) loginSucceeded(ctx context.Context, email string) error {
+	return u.loginAttempts.Reset(ctx, emailAttemptsKey(email))
+}
+
+// backoff returns the delay after the n-th failure: Backoff doubled n-1
+// times, capped at MaxBackoff.
+func (u *User) backoff(n int64) time.Duration {
+	delay := ttl(u.cfg.BruteForce.Backoff, LoginBackoff)
+	limit := ttl(u.cfg.BruteForce.MaxBackoff, LoginMaxBackoff)
+
+	for i := int64(1); i < n && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+
+	return delay
+}
+
+func emailAttemptsKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptsKey(ip string) string {
+	return "ip:" + ip
+}
+
+func orDefault(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+const (
+	GrantTypeClientCredentials = "client_credentials"
+
+	clientIDBytes     = 12
+	clientSecretBytes = 32
+)
+
+var supportedGrantTypes = []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials}
+
+// defaultGrantTypes are given to a client registered or updated without any.
+var defaultGrantTypes = []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken}
+
+type Client struct {
+	repo   drivers.DataStore
+	logger *logger.Logger
+}
+
+func NewClient(repo drivers.DataStore, logger *logger.Logger) *Client {
+	return &Client{repo: repo, logger: logger}
+}
+
+func (c *Client) Clients(ctx context.Context) ([]*entity.Client, error) {
+	return c.repo.GetClients(ctx)
+}
+
+func (c *Client) GetClient(ctx context.Context, clientID string) (*entity.Client, error) {
+	return c.repo.GetClientByClientID(ctx, clientID)
+}
+
+// CreateClient registers a client. The returned secret is not stored and
+// cannot be shown again.
+func (c *Client) CreateClient(ctx context.Context, req *dto.CreateClientRequest) (*entity.Client, string, error) {
+	client := &entity.Client{
+		ClientID:     req.ClientID,
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		GrantTypes:   req.GrantTypes,
+		CreatedAt:    time.Now(),
+	}
+
+	if len(client.GrantTypes) == 0 {
+		client.GrantTypes = defaultGrantTypes
+	}
+
+	if req.Public && client.AllowsGrant(GrantTypeClientCredentials) {
+		return nil, "", fmt.Errorf("%w: public clients cannot use %s", ErrInvalidClientMetadata, GrantTypeClientCredentials)
+	}
+
+	if err := validateClient(client); err != nil {
+		return nil, "", err
+	}
+
+	var err error
+	if client.ClientID == "" {
+		client.ClientID, err = randomString(clientIDBytes)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	var secret string
+	if !req.Public {
+		secret, client.SecretHash, err = newClientSecret()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if _, err = c.repo.CreateClient(ctx, client); err != nil {
+		return nil, "", err
+	}
+
+	c.logger.Info("oauth client registered", zap.String("client_id", client.ClientID))
+
+	return client, secret, nil
+}
+
+func (c *Client) UpdateClient(ctx context.Context, clientID string, req *dto.UpdateClientRequest) (*entity.Client, error) {
+	client, err := c.repo.GetClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Name = req.Name
+	client.RedirectURIs = req.RedirectURIs
+	client.Scopes = req.Scopes
+	client.GrantTypes = req.GrantTypes
+
+	if len(client.GrantTypes) == 0 {
+		client.GrantTypes = defaultGrantTypes
+	}
+
+	if client.Public() && client.AllowsGrant(GrantTypeClientCredentials) {
+		return nil, fmt.Errorf("%w: public clients cannot use %s", ErrInvalidClientMetadata, GrantTypeClientCredentials)
+	}
+
+	if err = validateClient(client); err != nil {
+		return nil, err
+	}
+
+	return client, c.repo.UpdateClient(ctx, client)
+}
+
+func (c *Client) DeleteClient(ctx context.Context, clientID string) error {
+	return c.repo.DeleteClient(ctx, clientID)
+}
+
+// RotateSecret replaces the secret of a confidential client.
+func (c *Client) RotateSecret(ctx context.Context, clientID string) (*entity.Client, string, error) {
+	client, err := c.repo.GetClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if client.Public() {
+		return nil, "", fmt.Errorf("%w: public clients have no secret", ErrInvalidClientMetadata)
+	}
+
+	secret, hash, err := newClientSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	client.SecretHash = hash
+
+	return client, secret, c.repo.UpdateClient(ctx, client)
+}
+
+// AuthenticateClient checks the client credentials presented to the token
+// endpoint. Public clients authenticate with their client_id only.
+func (c *Client) AuthenticateClient(ctx context.Context, clientID, secret string) (*entity.Client, error) {
+	client, err := c.repo.GetClientByClientID(ctx, clientID)
+	if errors.Is(err, drivers.ErrClientNotFound) {
+		return nil, oauthError("invalid_client", "unknown client_id")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if client.Public() {
+		if secret != "" {
+			return nil, oauthError("invalid_client", "public clients must not send a secret")
+		}
+		return client, nil
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(secret)) != nil {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+
+	return client, nil
+}
+
+func validateClient(client *entity.Client) error {
+	for _, grantType := range client.GrantTypes {
+		if !contains(supportedGrantTypes, grantType) {
+			return fmt.Errorf("%w: unsupported grant type %q", ErrInvalidClientMetadata, grantType)
+		}
+	}
+
+	if client.AllowsGrant(GrantTypeAuthorizationCode) && len(client.RedirectURIs) == 0 {
+		return fmt.Errorf("%w: redirect_uris are required for %s", ErrInvalidClientMetadata, GrantTypeAuthorizationCode)
+	}
+
+	return nil
+}
+
+func newClientSecret() (string, string, error) {
+	secret, err := randomString(clientSecretBytes)
+	if err != nil {
+		return "", "", err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, string(hash), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package usecase
+
+import "errors"
+
+var (
+	ErrInvalidCredentials    = errors.New("invalid email or password")
+	ErrInvalidRefreshToken   = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused    = errors.New("refresh token has already been used")
+	ErrInvalidAccessToken    = errors.New("access token is invalid or expired")
+	ErrTokenRevoked          = errors.New("token has been revoked")
+	ErrTokenClientMismatch   = errors.New("token was not issued to the client")
+	ErrInvalidClientMetadata = errors.New("invalid client metadata")
+	ErrUnknownRole           = errors.New("unknown role")
+	ErrMFARequired           = errors.New("multi-factor authentication required")
+	ErrInvalidMFAToken       = errors.New("mfa token is invalid or expired")
+	ErrInvalidMFACode        = errors.New("invalid authentication code")
+	ErrMFAAlreadyEnabled     = errors.New("multi-factor authentication is already enabled")
+	ErrMFANotEnrolled        = errors.New("no authenticator enrollment in progress")
+	ErrMFANotEnabled         = errors.New("multi-factor authentication is not enabled")
+	ErrInvalidCeremony       = errors.New("passkey ceremony is invalid or expired")
+	ErrPasskeyVerification   = errors.New("passkey verification failed")
+	ErrPasskeyCloned         = errors.New("passkey signature counter did not increase")
+	ErrEmailNotVerified      = errors.New("email address is not verified")
+	ErrInvalidVerification   = errors.New("verification token is invalid or expired")
+	ErrInvalidResetToken     = errors.New("password reset token is invalid or expired")
+	ErrWeakPassword          = errors.New("password does not meet the policy")
+	ErrPasswordReused        = errors.New("password was used recently")
+	ErrWrongPassword         = errors.New("current password is incorrect")
+	ErrAccountLocked         = errors.New("account is temporarily locked after too many failed logins")
+	ErrTooManyAttempts       = errors.New("too many failed login attempts")
+	ErrInvalidWebhook        = errors.New("invalid webhook endpoint")
+	ErrInvalidProfile        = errors.New("invalid user profile")
+	ErrUserNotDeleted        = errors.New("user is not deleted")
+	ErrInvalidUserQuery      = errors.New("invalid user query")
+	ErrInsufficientScope     = errors.New("access token scope is insufficient")
+)
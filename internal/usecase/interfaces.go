@@ -3,20 +3,98 @@ package usecase
 
 import (
 	"context"
+	"github.com/golang-jwt/jwt"
 	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
 	"github.com/madyar997/sso-jcode/internal/entity"
+	"time"
 )
 
 type (
 
 	// User
 	UserUseCase interface {
-		Users(ctx context.Context) ([]*entity.User, error)
-		CreateUser(ctx context.Context, user *entity.User) (int, error)
+		Users(ctx context.Context, req *dto.UserQueryRequest) (*UserPage, error)
+		CreateUser(ctx context.Context, req *dto.CreateUserRequest) (int, error)
 		GetUserByEmail(ctx context.Context, id string) (*entity.User, error)
 		GetUserByID(ctx context.Context, id int) (*entity.User, error)
+		SetRoles(ctx context.Context, id int, roles, permissions []string) error
+		UnlockUser(ctx context.Context, id int) error
+		UpdateUser(ctx context.Context, user *entity.User) (*entity.User, error)
+		PatchUser(ctx context.Context, id int, patch *entity.UserPatch) (*entity.User, error)
+		DeleteUser(ctx context.Context, id int) error
+		RestoreUser(ctx context.Context, id int) (*entity.User, error)
+		HardDeleteUser(ctx context.Context, id int) error
 
 		Register(ctx context.Context, email, password string) error
-		Login(ctx context.Context, email, password string) (*dto.LoginResponse, error)
+		VerifyEmail(ctx context.Context, token string) error
+		ResendVerification(ctx context.Context, email string) error
+		ForgotPassword(ctx context.Context, email string) error
+		ResetPassword(ctx context.Context, token, password string) error
+		ChangePassword(ctx context.Context, userID int, currentPassword, newPassword string) error
+		Login(ctx context.Context, email, password string, origin entity.Origin) (*dto.LoginResponse, error)
+		LoginMFA(ctx context.Context, mfaToken, code string, origin entity.Origin) (*dto.LoginResponse, error)
+		Authenticate(ctx context.Context, email, password, clientIP string) (*entity.User, error)
+		Refresh(ctx context.Context, refreshToken string) (*dto.LoginResponse, error)
+		Logout(ctx context.Context, claims jwt.MapClaims, allSessions bool) error
+		Sessions(ctx context.Context, userID int) ([]*entity.Session, error)
+		EndSession(ctx context.Context, userID int, sessionID string) error
+		EndSessions(ctx context.Context, userID int) error
+		RevokeToken(ctx context.Context, token, tokenTypeHint, clientID string) error
+		VerifyAccessToken(ctx context.Context, token string) (jwt.MapClaims, error)
+		Introspect(ctx context.Context, token, tokenTypeHint string) (*dto.IntrospectionResponse, error)
+
+		EnrollTOTP(ctx context.Context, userID int) (*dto.TOTPEnrollment, error)
+		ConfirmTOTP(ctx context.Context, userID int, code string) ([]string, error)
+		DisableMFA(ctx context.Context, userID int, code string) error
+	}
+
+	// OAuth
+	OAuthUseCase interface {
+		ValidateClient(ctx context.Context, clientID, redirectURI string) (string, error)
+		Authorize(ctx context.Context, req *dto.AuthorizeRequest, userID int, authTime time.Time,
+			origin entity.Origin) (string, error)
+		Token(ctx context.Context, req *dto.TokenRequest) (*dto.TokenResponse, error)
+		Introspect(ctx context.Context, req *dto.IntrospectionRequest) (*dto.IntrospectionResponse, error)
+		Revoke(ctx context.Context, req *dto.RevocationRequest) error
+		UserInfo(ctx context.Context, claims jwt.MapClaims) (map[string]interface{}, error)
+		Discovery() *dto.OpenIDConfiguration
+	}
+
+	// Passkey
+	PasskeyUseCase interface {
+		Passkeys(ctx context.Context, userID int) ([]*entity.Passkey, error)
+		DeletePasskey(ctx context.Context, userID int, credentialID string) error
+		BeginRegistration(ctx context.Context, userID int) (*dto.PasskeyCeremony, error)
+		FinishRegistration(ctx context.Context, userID int, req *dto.PasskeyFinishRequest) (*entity.Passkey, error)
+		BeginLogin(ctx context.Context) (*dto.PasskeyCeremony, error)
+		FinishLogin(ctx context.Context, req *dto.PasskeyFinishRequest, origin entity.Origin) (*dto.LoginResponse, error)
+	}
+
+	// Audit
+	AuditUseCase interface {
+		AuditEvents(ctx context.Context, req *dto.AuditQueryRequest) ([]*entity.AuditEvent, int64, error)
+	}
+
+	// Webhook
+	WebhookUseCase interface {
+		Webhooks(ctx context.Context) ([]*entity.WebhookEndpoint, error)
+		GetWebhook(ctx context.Context, id int) (*entity.WebhookEndpoint, error)
+		CreateWebhook(ctx context.Context, req *dto.CreateWebhookRequest) (*entity.WebhookEndpoint, error)
+		UpdateWebhook(ctx context.Context, id int, req *dto.UpdateWebhookRequest) (*entity.WebhookEndpoint, error)
+		DeleteWebhook(ctx context.Context, id int) error
+		RotateWebhookSecret(ctx context.Context, id int) (*entity.WebhookEndpoint, error)
+		Deliveries(ctx context.Context, id int, req *dto.WebhookDeliveriesRequest) ([]*entity.WebhookDelivery, int64, error)
+		GetDelivery(ctx context.Context, id, deliveryID int) (*entity.WebhookDelivery, error)
+		ReplayDelivery(ctx context.Context, id, deliveryID int) (*entity.WebhookDelivery, error)
+	}
+
+	// Client
+	ClientUseCase interface {
+		Clients(ctx context.Context) ([]*entity.Client, error)
+		GetClient(ctx context.Context, clientID string) (*entity.Client, error)
+		CreateClient(ctx context.Context, req *dto.CreateClientRequest) (*entity.Client, string, error)
+		UpdateClient(ctx context.Context, clientID string, req *dto.UpdateClientRequest) (*entity.Client, error)
+		DeleteClient(ctx context.Context, clientID string) error
+		RotateSecret(ctx context.Context, clientID string) (*entity.Client, string, error)
 	}
 )
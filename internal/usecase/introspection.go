@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/opentracing/opentracing-go"
+	"strconv"
+	"strings"
+)
+
+// Introspect implements RFC 7662 for access and refresh tokens. Unknown,
+// expired, rotated or revoked tokens are reported as inactive, not as an
+// error. An unknown hint is ignored as the RFC allows.
+func (u *User) Introspect(ctx context.Context, token, tokenTypeHint string) (*dto.IntrospectionResponse, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "introspect token use case")
+	defer span.Finish()
+
+	introspectors := []func(context.Context, string) (*dto.IntrospectionResponse, error){
+		u.introspectAccessToken, u.introspectRefreshToken,
+	}
+	if tokenTypeHint == TokenTypeRefresh {
+		introspectors[0], introspectors[1] = introspectors[1], introspectors[0]
+	}
+
+	for _, introspect := range introspectors {
+		response, err := introspect(spanCtx, token)
+		if err != nil || response.Active {
+			return response, err
+		}
+	}
+
+	return &dto.IntrospectionResponse{Active: false}, nil
+}
+
+func (u *User) introspectAccessToken(ctx context.Context, token string) (*dto.IntrospectionResponse, error) {
+	claims, err := u.VerifyAccessToken(ctx, token)
+	if errors.Is(err, ErrInvalidAccessToken) || errors.Is(err, ErrTokenRevoked) {
+		return &dto.IntrospectionResponse{Active: false}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens of OAuth clients report the granted scope, first party tokens the
+	// permissions of the user.
+	scope := claimString(claims, "scope")
+	if _, delegated := claims["client_id"]; !delegated {
+		scope = strings.Join(GrantedScopes(claims), " ")
+	}
+
+	return &dto.IntrospectionResponse{
+		Active:    true,
+		Scope:     scope,
+		ClientID:  claimString(claims, "client_id"),
+		Username:  claimString(claims, "email"),
+		TokenType: "Bearer",
+		Exp:       claimTime(claims, "exp").Unix(),
+		Iat:       claimTime(claims, "iat").Unix(),
+		Sub:       claimString(claims, "sub"),
+		Iss:       claimString(claims, "iss"),
+		Jti:       claimString(claims, "jti"),
+		UserID:    claimInt(claims, "user_id"),
+	}, nil
+}
+
+func (u *User) introspectRefreshToken(ctx context.Context, token string) (*dto.IntrospectionResponse, error) {
+	hash := hashToken(token)
+
+	record, err := u.refreshTokens.Get(ctx, hash)
+	if err != nil || record == nil {
+		return &dto.IntrospectionResponse{Active: false}, err
+	}
+
+	used, err := u.refreshTokens.IsUsed(ctx, hash)
+	if err != nil || used {
+		return &dto.IntrospectionResponse{Active: false}, err
+	}
+
+	return &dto.IntrospectionResponse{
+		Active:    true,
+		Scope:     record.Scope,
+		ClientID:  record.ClientID,
+		TokenType: TokenTypeRefresh,
+		Exp:       record.ExpiresAt.Unix(),
+		Sub:       strconv.Itoa(record.UserID),
+		Iss:       u.cfg.Issuer,
+		UserID:    record.UserID,
+	}, nil
+}
+
+// GrantedScopes returns what an access token may do. Client credential tokens
+// are limited to their scope, user tokens to the permissions of the user and,
+// when issued to an OAuth client, additionally to the scope granted to it.
+func GrantedScopes(claims jwt.MapClaims) []string {
+	scopes := strings.Fields(claimString(claims, "scope"))
+
+	if claimString(claims, "gty") == GrantTypeClientCredentials {
+		return scopes
+	}
+
+	_, delegated := claims["client_id"]
+
+	granted := make([]string, 0)
+	permissions, _ := claims["permissions"].([]interface{})
+	for _, p := range permissions {
+		permission, _ := p.(string)
+		if !delegated || contains(scopes, permission) {
+			granted = append(granted, permission)
+		}
+	}
+
+	return granted
+}
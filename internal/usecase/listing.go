@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/opentracing/opentracing-go"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	UserPageSize    = 50
+	UserMaxPageSize = 500
+)
+
+// UserPage is a page of the user list.
+type UserPage struct {
+	Users []*entity.User
+	// Total counts every user matching the filters, not only this page.
+	Total int64
+	// NextCursor continues after the last user of the page, it is empty on
+	// the last page.
+	NextCursor string
+}
+
+// userCursor is the position after a user in the sorted list. It is handed
+// out base64 encoded and only continues the sort it was made for.
+type userCursor struct {
+	SortBy    string `json:"s"`
+	SortOrder string `json:"o"`
+	Value     string `json:"v,omitempty"`
+	ID        int    `json:"id"`
+}
+
+// Users returns a page of the users matching req, by id unless sorted
+// otherwise. The limit and the sort of req are set to the ones used. A
+// cursor keeps the sort of the page it came from and replaces the offset.
+func (u *User) Users(ctx context.Context, req *dto.UserQueryRequest) (*UserPage, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "users use case")
+	defer span.Finish()
+
+	switch {
+	case req.Limit <= 0:
+		req.Limit = UserPageSize
+	case req.Limit > UserMaxPageSize:
+		req.Limit = UserMaxPageSize
+	}
+
+	req.SortOrder = strings.ToLower(req.SortOrder)
+
+	query := &drivers.SearchQuery{
+		Email:       req.Email,
+		Name:        req.Name,
+		MinAge:      req.MinAge,
+		MaxAge:      req.MaxAge,
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+		// one more than asked tells whether there is a next page
+		Limit:  req.Limit + 1,
+		Offset: req.Offset,
+	}
+
+	if req.Cursor != "" {
+		cursor, err := decodeUserCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if req.SortBy != "" && req.SortBy != cursor.SortBy || req.SortOrder != "" && req.SortOrder != cursor.SortOrder {
+			return nil, fmt.Errorf("%w: cursor belongs to another sort", ErrInvalidUserQuery)
+		}
+
+		req.SortBy, req.SortOrder, req.Offset = cursor.SortBy, cursor.SortOrder, 0
+		query.AfterID = cursor.ID
+		if query.AfterValue, err = cursor.value(); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.SortBy == "" {
+		req.SortBy = "id"
+	}
+	if req.SortOrder == "" {
+		req.SortOrder = drivers.SortAsc
+	}
+	query.SortBy, query.SortOrder = req.SortBy, req.SortOrder
+
+	if err := validateUserQuery(req); err != nil {
+		return nil, err
+	}
+
+	users, total, err := u.repo.GetUsers(spanCtx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &UserPage{Users: users, Total: total}
+	if len(users) > req.Limit {
+		page.Users = users[:req.Limit]
+		page.NextCursor = encodeUserCursor(req.SortBy, req.SortOrder, page.Users[req.Limit-1])
+	}
+
+	return page, nil
+}
+
+func validateUserQuery(req *dto.UserQueryRequest) error {
+	if !contains(drivers.UserSortFields, req.SortBy) {
+		return fmt.Errorf("%w: cannot sort by %q, use one of %s", ErrInvalidUserQuery, req.SortBy,
+			strings.Join(drivers.UserSortFields, ", "))
+	}
+	if req.SortOrder != drivers.SortAsc && req.SortOrder != drivers.SortDesc {
+		return fmt.Errorf("%w: sort order must be %s or %s", ErrInvalidUserQuery, drivers.SortAsc, drivers.SortDesc)
+	}
+	if req.MinAge != nil && req.MaxAge != nil && *req.MinAge > *req.MaxAge {
+		return fmt.Errorf("%w: min_age is greater than max_age", ErrInvalidUserQuery)
+	}
+	return nil
+}
+
+func encodeUserCursor(sortBy, sortOrder string, last *entity.User) string {
+	cursor := userCursor{SortBy: sortBy, SortOrder: sortOrder, ID: last.Id}
+
+	switch sortBy {
+	case "name":
+		cursor.Value = last.Name
+	case "email":
+		cursor.Value = last.Email
+	case "age":
+		cursor.Value = strconv.Itoa(last.Age)
+	case "created_at":
+		cursor.Value = last.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeUserCursor(encoded string) (*userCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidUserQuery)
+	}
+
+	cursor := new(userCursor)
+	if err = json.Unmarshal(raw, cursor); err != nil || cursor.ID == 0 {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidUserQuery)
+	}
+	return cursor, nil
+}
+
+// value is the sort field of the user the cursor points after, typed like
+// the field.
+func (c *userCursor) value() (interface{}, error) {
+	switch c.SortBy {
+	case "id":
+		return c.ID, nil
+	case "name", "email":
+		return c.Value, nil
+	case "age":
+		if age, err := strconv.Atoi(c.Value); err == nil {
+			return age, nil
+		}
+	case "created_at":
+		if createdAt, err := time.Parse(time.RFC3339Nano, c.Value); err == nil {
+			return createdAt, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidUserQuery)
+}
@@ -0,0 +1,306 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"go.uber.org/zap"
+	"image/png"
+	"strings"
+	"time"
+)
+
+const (
+	MFAChallengeTTL = 300
+
+	maxMFAAttempts    = 5
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 5
+	totpPeriod        = 30
+	totpSkew          = 1
+	qrCodeSize        = 256
+)
+
+// EnrollTOTP provisions a new authenticator secret. MFA is not enabled until
+// ConfirmTOTP receives a valid code; enrolling again replaces the pending secret.
+func (u *User) EnrollTOTP(ctx context.Context, userID int) (*dto.TOTPEnrollment, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "enroll totp use case")
+	defer span.Finish()
+
+	user, err := u.repo.GetUserByID(spanCtx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      u.mfaIssuer(),
+		AccountName: user.Email,
+		Period:      totpPeriod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	qrCode, err := qrCodeDataURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = u.repo.UpdateUserMFA(spanCtx, user.Id, key.Secret(), false, nil); err != nil {
+		return nil, err
+	}
+
+	return &dto.TOTPEnrollment{
+		Secret:     key.Secret(),
+		OTPAuthURI: key.URL(),
+		QRCode:     qrCode,
+	}, nil
+}
+
+// ConfirmTOTP enables MFA once the user proves the authenticator works and
+// returns the recovery codes, they are not shown again.
+func (u *User) ConfirmTOTP(ctx context.Context, userID int, code string) ([]string, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "confirm totp use case")
+	defer span.Finish()
+
+	user, err := u.repo.GetUserByID(spanCtx, userID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case user.MFAEnabled:
+		return nil, ErrMFAAlreadyEnabled
+	case user.TOTPSecret == "":
+		return nil, ErrMFANotEnrolled
+	}
+
+	valid, err := u.validTOTP(spanCtx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = u.repo.UpdateUserMFA(spanCtx, user.Id, user.TOTPSecret, true, hashes); err != nil {
+		return nil, err
+	}
+
+	u.logger.Info("mfa enabled", zap.Int("user_id", user.Id))
+
+	return codes, nil
+}
+
+// DisableMFA turns MFA off, it needs a current TOTP or recovery code.
+func (u *User) DisableMFA(ctx context.Context, userID int, code string) error {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "disable mfa use case")
+	defer span.Finish()
+
+	user, err := u.repo.GetUserByID(spanCtx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+
+	valid, err := u.verifySecondFactor(spanCtx, user, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrInvalidMFACode
+	}
+
+	u.logger.Info("mfa disabled", zap.Int("user_id", user.Id))
+
+	return u.repo.UpdateUserMFA(spanCtx, user.Id, "", false, nil)
+}
+
+// LoginMFA completes a login started by Login for a user with MFA enabled.
+// A challenge allows a limited number of wrong codes, each one also counts as
+// a failed login of the email.
+func (u *User) LoginMFA(ctx context.Context, mfaToken, code string, origin entity.Origin) (*dto.LoginResponse, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "login mfa use case")
+	defer span.Finish()
+
+	hash := hashToken(mfaToken)
+
+	challenge, err := u.mfa.GetChallenge(spanCtx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	// counted before the code is checked, concurrent guesses cannot get past the limit
+	attempts, err := u.mfa.CountAttempt(spanCtx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if attempts == 0 || attempts > maxMFAAttempts {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := u.repo.GetUserByID(spanCtx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, err := u.verifySecondFactor(spanCtx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		u.recordLoginFailed(spanCtx, user.Email, user, loginFailedWrongMFACode)
+		if err = u.loginFailed(spanCtx, user.Email, origin.IP); err != nil {
+			return nil, err
+		}
+
+		if attempts == maxMFAAttempts {
+			u.logger.Warn("too many mfa attempts, challenge dropped", zap.Int("user_id", user.Id))
+			if err = u.mfa.DeleteChallenge(spanCtx, hash); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, ErrInvalidMFACode
+	}
+
+	if err = u.mfa.DeleteChallenge(spanCtx, hash); err != nil {
+		return nil, err
+	}
+
+	if err = u.loginSucceeded(spanCtx, user.Email); err != nil {
+		return nil, err
+	}
+
+	return u.issueTokens(spanCtx, user, &entity.RefreshToken{AuthTime: challenge.AuthTime}, origin)
+}
+
+// mfaChallenge answers a correct password of a user with MFA enabled.
+func (u *User) mfaChallenge(ctx context.Context, user *entity.User) (*dto.LoginResponse, error) {
+	mfaToken, err := randomString(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.mfa.SaveChallenge(ctx, hashToken(mfaToken), &entity.MFAChallenge{
+		UserID:   user.Id,
+		AuthTime: time.Now(),
+	}, MFAChallengeTTL*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginResponse{
+		Name:        user.Name,
+		Email:       user.Email,
+		MFARequired: true,
+		MFAToken:    mfaToken,
+	}, nil
+}
+
+// verifySecondFactor accepts a TOTP code or an unused recovery code, which is
+// spent on success.
+func (u *User) verifySecondFactor(ctx context.Context, user *entity.User, code string) (bool, error) {
+	code = normalizeCode(code)
+
+	if len(code) == int(otp.DigitsSix) {
+		return u.validTOTP(ctx, user, code)
+	}
+
+	hash := hashToken(code)
+	if !contains(user.RecoveryCodes, hash) {
+		return false, nil
+	}
+
+	// removed in the datastore, a concurrent login with the same code or a
+	// disable in between leaves nothing to remove and the code is refused
+	spent, err := u.repo.RemoveRecoveryCode(ctx, user.Id, hash)
+	if err != nil || !spent {
+		return false, err
+	}
+
+	u.logger.Info("recovery code used", zap.Int("user_id", user.Id), zap.Int("remaining", len(user.RecoveryCodes)-1))
+
+	return true, nil
+}
+
+// validTOTP checks the code against the user's secret; a code accepted once
+// is refused for the rest of its validity window.
+func (u *User) validTOTP(ctx context.Context, user *entity.User, code string) (bool, error) {
+	code = normalizeCode(code)
+
+	valid, err := totp.ValidateCustom(code, user.TOTPSecret, time.Now(), totp.ValidateOpts{
+		Period:    totpPeriod,
+		Skew:      totpSkew,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	if err != nil || !valid {
+		return false, nil
+	}
+
+	return u.mfa.MarkCodeUsed(ctx, user.Id, code, (2*totpSkew+1)*totpPeriod*time.Second)
+}
+
+func (u *User) mfaIssuer() string {
+	if u.cfg.App.Name != "" {
+		return u.cfg.App.Name
+	}
+	return "sso"
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeCode drops the separators users type or copy along with a code.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+func qrCodeDataURL(key *otp.Key) (string, error) {
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,348 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/madyar997/sso-jcode/config"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/pkg/cache"
+	"github.com/opentracing/opentracing-go"
+	"strings"
+	"time"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+
+	ResponseTypeCode        = "code"
+	CodeChallengeMethodS256 = "S256"
+
+	AuthorizationCodeTTL = 60
+
+	// RFC 7636 section 4.1 bounds for the code verifier, the S256 challenge
+	// is always 43 characters.
+	minCodeVerifierLength = 43
+	maxCodeVerifierLength = 128
+)
+
+// OAuthError is an error response defined by RFC 6749 section 4.1.2.1 and 5.2.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code, description string) error {
+	return &OAuthError{Code: code, Description: description}
+}
+
+type OAuth struct {
+	cfg     *config.Config
+	user    *User
+	clients *Client
+	codes   cache.AuthorizationCode
+}
+
+func NewOAuth(user *User, clients *Client, codes cache.AuthorizationCode, cfg *config.Config) *OAuth {
+	return &OAuth{cfg: cfg, user: user, clients: clients, codes: codes}
+}
+
+// ValidateClient checks the client and returns the redirect uri to use. An
+// error here means the request must not be redirected back to the client.
+func (o *OAuth) ValidateClient(ctx context.Context, clientID, redirectURI string) (string, error) {
+	client, err := o.clients.GetClient(ctx, clientID)
+	if errors.Is(err, drivers.ErrClientNotFound) {
+		return "", oauthError("invalid_client", "unknown client_id")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !client.AllowsGrant(GrantTypeAuthorizationCode) {
+		return "", oauthError("unauthorized_client", "client may not use the authorization code grant")
+	}
+
+	if redirectURI == "" {
+		if len(client.RedirectURIs) != 1 {
+			return "", oauthError("invalid_request", "redirect_uri is required")
+		}
+		return client.RedirectURIs[0], nil
+	}
+
+	if !client.AllowsRedirectURI(redirectURI) {
+		return "", oauthError("invalid_request", "redirect_uri is not registered for the client")
+	}
+
+	return redirectURI, nil
+}
+
+// Authorize issues a one-time authorization code for the user who
+// authenticated at authTime. The session started by exchanging the code is
+// recorded with origin, the browser of the user.
+func (o *OAuth) Authorize(ctx context.Context, req *dto.AuthorizeRequest, userID int, authTime time.Time,
+	origin entity.Origin) (string, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "authorize use case")
+	defer span.Finish()
+
+	redirectURI, err := o.ValidateClient(spanCtx, req.ClientID, req.RedirectURI)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := o.clients.GetClient(spanCtx, req.ClientID)
+	if err != nil {
+		return "", err
+	}
+
+	allowed := client.Scopes
+	if len(allowed) == 0 && req.Scope != "" {
+		allowed = openIDScopes
+	}
+
+	scope, ok := grantScope(req.Scope, allowed)
+	if !ok {
+		return "", oauthError("invalid_scope", "requested scope is not allowed for the client")
+	}
+
+	if req.ResponseType != ResponseTypeCode {
+		return "", oauthError("unsupported_response_type", "only the code response type is supported")
+	}
+
+	if req.CodeChallenge == "" {
+		return "", oauthError("invalid_request", "code_challenge is required")
+	}
+
+	if req.CodeChallengeMethod != CodeChallengeMethodS256 {
+		return "", oauthError("invalid_request", "code_challenge_method must be S256")
+	}
+
+	code, err := randomString(refreshTokenBytes)
+	if err != nil {
+		return "", err
+	}
+
+	err = o.codes.Save(spanCtx, hashToken(code), &entity.AuthorizationCode{
+		ClientID:      req.ClientID,
+		RedirectURI:   redirectURI,
+		UserID:        userID,
+		Scope:         scope,
+		Nonce:         req.Nonce,
+		AuthTime:      authTime,
+		CodeChallenge: req.CodeChallenge,
+		Origin:        origin,
+		ExplicitURI:   req.RedirectURI != "",
+	}, ttl(o.cfg.AuthorizationCodeTTL, AuthorizationCodeTTL))
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// Token implements the token endpoint for the authorization_code,
+// refresh_token and client_credentials grants.
+func (o *OAuth) Token(ctx context.Context, req *dto.TokenRequest) (*dto.TokenResponse, error) {
+	if req.ClientID == "" {
+		return nil, oauthError("invalid_client", "client authentication is required")
+	}
+
+	client, err := o.clients.AuthenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials:
+	default:
+		return nil, oauthError("unsupported_grant_type", "grant_type is not supported")
+	}
+
+	if !client.AllowsGrant(req.GrantType) {
+		return nil, oauthError("unauthorized_client", "client may not use this grant type")
+	}
+
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		return o.exchangeCode(ctx, client, req)
+	case GrantTypeRefreshToken:
+		return o.refresh(ctx, client, req)
+	default:
+		return o.clientCredentials(client, req)
+	}
+}
+
+func (o *OAuth) exchangeCode(ctx context.Context, client *entity.Client, req *dto.TokenRequest) (*dto.TokenResponse, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "exchange authorization code use case")
+	defer span.Finish()
+
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, oauthError("invalid_request", "code and code_verifier are required")
+	}
+
+	record, err := o.codes.Take(spanCtx, hashToken(req.Code))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case record == nil:
+		return nil, oauthError("invalid_grant", "authorization code is invalid or expired")
+	case record.ClientID != client.ClientID:
+		return nil, oauthError("invalid_grant", "authorization code was issued to another client")
+	case (record.ExplicitURI || req.RedirectURI != "") && req.RedirectURI != record.RedirectURI:
+		return nil, oauthError("invalid_grant", "redirect_uri does not match the authorization request")
+	case !verifyCodeChallenge(req.CodeVerifier, record.CodeChallenge):
+		return nil, oauthError("invalid_grant", "code_verifier does not match the code challenge")
+	}
+
+	user, err := o.user.repo.GetUserByID(spanCtx, record.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := o.user.issueTokens(spanCtx, user, &entity.RefreshToken{
+		ClientID: record.ClientID,
+		Scope:    record.Scope,
+		AuthTime: record.AuthTime,
+	}, record.Origin)
+	if err != nil {
+		return nil, err
+	}
+
+	response := o.tokenResponse(tokens, record.Scope)
+
+	if hasScope(record.Scope, ScopeOpenID) {
+		response.IDToken, err = o.idToken(user, record)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+func (o *OAuth) refresh(ctx context.Context, client *entity.Client, req *dto.TokenRequest) (*dto.TokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, oauthError("invalid_request", "refresh_token is required")
+	}
+
+	tokens, err := o.user.refresh(ctx, req.RefreshToken, client.ClientID)
+	if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+		return nil, oauthError("invalid_grant", err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return o.tokenResponse(tokens, ""), nil
+}
+
+// clientCredentials issues a machine token for service to service calls,
+// limited to the scopes registered for the client.
+func (o *OAuth) clientCredentials(client *entity.Client, req *dto.TokenRequest) (*dto.TokenResponse, error) {
+	if client.Public() {
+		return nil, oauthError("unauthorized_client", "public clients cannot use client credentials")
+	}
+
+	scope, ok := grantScope(req.Scope, client.Scopes)
+	if !ok {
+		return nil, oauthError("invalid_scope", "requested scope is not allowed for the client")
+	}
+
+	accessToken, err := o.user.issueClientToken(client, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(o.user.accessTokenTTL() / time.Second),
+		Scope:       scope,
+	}, nil
+}
+
+// Introspect serves the introspection endpoint, only authenticated
+// confidential clients may ask about tokens.
+func (o *OAuth) Introspect(ctx context.Context, req *dto.IntrospectionRequest) (*dto.IntrospectionResponse, error) {
+	if req.ClientID == "" {
+		return nil, oauthError("invalid_client", "client authentication is required")
+	}
+
+	client, err := o.clients.AuthenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if client.Public() {
+		return nil, oauthError("invalid_client", "public clients cannot introspect tokens")
+	}
+
+	return o.user.Introspect(ctx, req.Token, req.TokenTypeHint)
+}
+
+// Revoke authenticates the client and revokes a token issued to it, RFC 7009
+// section 2.1. Public clients are identified by their client_id alone.
+func (o *OAuth) Revoke(ctx context.Context, req *dto.RevocationRequest) error {
+	if req.ClientID == "" {
+		return oauthError("invalid_client", "client authentication is required")
+	}
+
+	client, err := o.clients.AuthenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	err = o.user.RevokeToken(ctx, req.Token, req.TokenTypeHint, client.ClientID)
+	if errors.Is(err, ErrTokenClientMismatch) {
+		return oauthError("unauthorized_client", err.Error())
+	}
+	return err
+}
+
+func (o *OAuth) tokenResponse(tokens *dto.LoginResponse, scope string) *dto.TokenResponse {
+	return &dto.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(o.user.accessTokenTTL() / time.Second),
+		RefreshToken: tokens.RefreshToken,
+		Scope:        scope,
+	}
+}
+
+// verifyCodeChallenge checks the PKCE S256 transformation from RFC 7636 section 4.6.
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < minCodeVerifierLength || len(verifier) > maxCodeVerifierLength {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// grantScope checks the requested scope against the scopes allowed for the
+// client. An empty request grants all allowed scopes, nothing is granted
+// beyond them.
+func grantScope(requested string, allowed []string) (string, bool) {
+	if requested == "" {
+		return strings.Join(allowed, " "), true
+	}
+
+	for _, scope := range strings.Fields(requested) {
+		if !contains(allowed, scope) {
+			return "", false
+		}
+	}
+
+	return requested, true
+}
@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ScopeOpenID  = "openid"
+	ScopeEmail   = "email"
+	ScopeProfile = "profile"
+)
+
+// openIDScopes are the scopes a client may request for signing a user in even
+// when it has no scopes registered.
+var openIDScopes = []string{ScopeOpenID, ScopeEmail, ScopeProfile}
+
+// idToken issues the OpenID Connect ID token for an exchanged authorization code.
+func (o *OAuth) idToken(user *entity.User, code *entity.AuthorizationCode) (string, error) {
+	now := time.Now()
+
+	claims := jwt.MapClaims{
+		"iss":       o.cfg.Issuer,
+		"sub":       strconv.Itoa(user.Id),
+		"aud":       code.ClientID,
+		"iat":       now.Unix(),
+		"exp":       now.Add(o.user.accessTokenTTL()).Unix(),
+		"auth_time": code.AuthTime.Unix(),
+	}
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+	for name, value := range profileClaims(user, code.Scope) {
+		claims[name] = value
+	}
+
+	return o.user.signer.Sign(claims)
+}
+
+// UserInfo returns the claims about the owner of a verified access token.
+// Following OpenID Connect Core 5.3 the token must have been granted the
+// openid scope, client credential tokens have no owner and are invalid here.
+func (o *OAuth) UserInfo(ctx context.Context, claims jwt.MapClaims) (map[string]interface{}, error) {
+	if _, ok := claims["user_id"]; !ok {
+		return nil, ErrInvalidAccessToken
+	}
+	if !hasScope(claimString(claims, "scope"), ScopeOpenID) {
+		return nil, ErrInsufficientScope
+	}
+
+	user, err := o.user.GetUserByID(ctx, claimInt(claims, "user_id"))
+	if errors.Is(err, drivers.ErrUserNotFound) {
+		return nil, ErrInvalidAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info := profileClaims(user, claimString(claims, "scope"))
+	info["sub"] = strconv.Itoa(user.Id)
+
+	return info, nil
+}
+
+func (o *OAuth) Discovery() *dto.OpenIDConfiguration {
+	issuer := strings.TrimSuffix(o.cfg.Issuer, "/")
+
+	return &dto.OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserinfoEndpoint:                  issuer + "/userinfo",
+		JwksURI:                           issuer + "/.well-known/jwks.json",
+		RevocationEndpoint:                issuer + "/oauth/revoke",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		ScopesSupported:                   openIDScopes,
+		ResponseTypesSupported:            []string{ResponseTypeCode},
+		GrantTypesSupported:               supportedGrantTypes,
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{o.user.signer.Algorithm()},
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{CodeChallengeMethodS256},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "email", "name"},
+	}
+}
+
+// profileClaims returns the standard claims released for the scope. Tokens
+// without a scope come from the password login and see every claim.
+func profileClaims(user *entity.User, scope string) map[string]interface{} {
+	claims := make(map[string]interface{})
+	all := scope == ""
+
+	if all || hasScope(scope, ScopeEmail) {
+		claims["email"] = user.Email
+	}
+	if all || hasScope(scope, ScopeProfile) {
+		claims["name"] = user.Name
+	}
+
+	return claims
+}
+
+func hasScope(scope, want string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
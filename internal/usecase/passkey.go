@@ -0,0 +1,305 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/madyar997/sso-jcode/config"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/pkg/cache"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+const PasskeyCeremonyTTL = 300
+
+// Passkey implements WebAuthn registration and passwordless login with
+// discoverable credentials.
+type Passkey struct {
+	user     *User
+	webauthn *webauthn.WebAuthn
+	sessions cache.WebAuthnSession
+}
+
+func NewPasskey(user *User, sessions cache.WebAuthnSession, cfg *config.Config) (*Passkey, error) {
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthn.RPID,
+		RPDisplayName: cfg.WebAuthn.RPDisplayName,
+		RPOrigins:     cfg.WebAuthn.RPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: PasskeyCeremonyTTL * time.Second},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: PasskeyCeremonyTTL * time.Second},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("webauthn config: %w", err)
+	}
+
+	return &Passkey{user: user, webauthn: wa, sessions: sessions}, nil
+}
+
+func (p *Passkey) Passkeys(ctx context.Context, userID int) ([]*entity.Passkey, error) {
+	return p.user.repo.GetPasskeys(ctx, userID)
+}
+
+func (p *Passkey) DeletePasskey(ctx context.Context, userID int, credentialID string) error {
+	return p.user.repo.DeletePasskey(ctx, userID, credentialID)
+}
+
+// BeginRegistration creates the options for navigator.credentials.create(),
+// passkeys the user already has are excluded.
+func (p *Passkey) BeginRegistration(ctx context.Context, userID int) (*dto.PasskeyCeremony, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "begin passkey registration use case")
+	defer span.Finish()
+
+	owner, err := p.passkeyUser(spanCtx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(owner.credentials))
+	for _, credential := range owner.credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	options, session, err := p.webauthn.BeginRegistration(owner,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.startCeremony(spanCtx, session, options)
+}
+
+// FinishRegistration verifies the attestation and stores the new passkey.
+func (p *Passkey) FinishRegistration(ctx context.Context, userID int, req *dto.PasskeyFinishRequest) (*entity.Passkey, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "finish passkey registration use case")
+	defer span.Finish()
+
+	session, err := p.sessions.Take(spanCtx, hashToken(req.CeremonyID))
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrInvalidCeremony
+	}
+
+	owner, err := p.passkeyUser(spanCtx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPasskeyVerification, protocolError(err))
+	}
+
+	credential, err := p.webauthn.CreateCredential(owner, *session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPasskeyVerification, protocolError(err))
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	passkey := &entity.Passkey{
+		UserID:          userID,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		Name:            req.Name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		CreatedAt:       time.Now(),
+	}
+
+	if _, err = p.user.repo.CreatePasskey(spanCtx, passkey); err != nil {
+		return nil, err
+	}
+
+	p.user.logger.Info("passkey registered", zap.Int("user_id", userID), zap.String("credential_id", passkey.CredentialID))
+
+	return passkey, nil
+}
+
+// BeginLogin creates the options for navigator.credentials.get(). The user is
+// not known yet, the authenticator picks a discoverable credential.
+func (p *Passkey) BeginLogin(ctx context.Context) (*dto.PasskeyCeremony, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "begin passkey login use case")
+	defer span.Finish()
+
+	options, session, err := p.webauthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.startCeremony(spanCtx, session, options)
+}
+
+// FinishLogin verifies the assertion and issues tokens like a password login.
+// User verification by the authenticator stands in for the second factor.
+func (p *Passkey) FinishLogin(ctx context.Context, req *dto.PasskeyFinishRequest, origin entity.Origin) (*dto.LoginResponse, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "finish passkey login use case")
+	defer span.Finish()
+
+	session, err := p.sessions.Take(spanCtx, hashToken(req.CeremonyID))
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrInvalidCeremony
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPasskeyVerification, protocolError(err))
+	}
+
+	var owner *passkeyUser
+	var passkey *entity.Passkey
+	var lookupErr error
+
+	credential, err := p.webauthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		passkey, lookupErr = p.user.repo.GetPasskeyByCredentialID(spanCtx, base64.RawURLEncoding.EncodeToString(rawID))
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+
+		owner, lookupErr = p.passkeyUser(spanCtx, passkey.UserID)
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+
+		return owner, nil
+	}, *session, parsed)
+	if lookupErr != nil && !errors.Is(lookupErr, drivers.ErrPasskeyNotFound) {
+		return nil, lookupErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPasskeyVerification, protocolError(err))
+	}
+
+	if credential.Authenticator.CloneWarning {
+		p.user.logger.Warn("passkey sign count did not increase, possible cloned authenticator",
+			zap.Int("user_id", passkey.UserID), zap.String("credential_id", passkey.CredentialID))
+
+		return nil, ErrPasskeyCloned
+	}
+
+	now := time.Now()
+	passkey.SignCount = credential.Authenticator.SignCount
+	passkey.BackupState = credential.Flags.BackupState
+	passkey.LastUsedAt = &now
+
+	if err = p.user.repo.UpdatePasskeyUsage(spanCtx, passkey); err != nil {
+		return nil, err
+	}
+
+	return p.user.issueTokens(spanCtx, owner.user, nil, origin)
+}
+
+func (p *Passkey) startCeremony(ctx context.Context, session *webauthn.SessionData, options interface{}) (*dto.PasskeyCeremony, error) {
+	ceremonyID, err := randomString(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.sessions.Save(ctx, hashToken(ceremonyID), session, PasskeyCeremonyTTL*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PasskeyCeremony{CeremonyID: ceremonyID, Options: options}, nil
+}
+
+func (p *Passkey) passkeyUser(ctx context.Context, userID int) (*passkeyUser, error) {
+	user, err := p.user.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := p.user.repo.GetPasskeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		id, err := base64.RawURLEncoding.DecodeString(passkey.CredentialID)
+		if err != nil {
+			return nil, err
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              id,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: passkey.SignCount,
+			},
+		})
+	}
+
+	return &passkeyUser{user: user, credentials: credentials}, nil
+}
+
+// passkeyUser adapts entity.User to webauthn.User. The user handle is the
+// numeric user id, it carries no personal data.
+type passkeyUser struct {
+	user        *entity.User
+	credentials []webauthn.Credential
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	return []byte(strconv.Itoa(u.user.Id))
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	if u.user.Name != "" {
+		return u.user.Name
+	}
+	return u.user.Email
+}
+
+func (u *passkeyUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+// protocolError adds the details go-webauthn keeps out of Error().
+func protocolError(err error) string {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) && protocolErr.Details != "" {
+		return protocolErr.Details
+	}
+	return err.Error()
+}
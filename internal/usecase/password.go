@@ -0,0 +1,194 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/pkg/mailer"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+	"net/url"
+	"strings"
+)
+
+const PasswordResetTTL = 3600
+
+// ForgotPassword mails a password reset link. Unknown addresses are ignored
+// so the caller learns nothing about which accounts exist.
+func (u *User) ForgotPassword(ctx context.Context, email string) error {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "forgot password use case")
+	defer span.Finish()
+
+	user, err := u.repo.GetUserByEmail(spanCtx, email)
+	if errors.Is(err, drivers.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := randomString(refreshTokenBytes)
+	if err != nil {
+		return err
+	}
+
+	resetTTL := ttl(u.cfg.PasswordResetTTL, PasswordResetTTL)
+
+	err = u.resets.Save(spanCtx, hashToken(token), &entity.PasswordReset{UserID: user.Id, Email: user.Email}, resetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := token
+	if u.cfg.PasswordResetURL != "" {
+		link = u.cfg.PasswordResetURL + "?" + url.Values{"token": {token}}.Encode()
+	}
+
+	return u.mailer.Send(spanCtx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Open the link below to choose a new password:\n\n%s\n\n"+
+			"The link expires in %s and works once. If you did not ask for a reset, ignore this email.\n",
+			link, resetTTL),
+	})
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. Every
+// session of the user is ended, whoever knew the old password is logged out.
+// A password rejected by the policy leaves the token usable for another try.
+func (u *User) ResetPassword(ctx context.Context, token, password string) error {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "reset password use case")
+	defer span.Finish()
+
+	hash := hashToken(token)
+
+	reset, err := u.resets.Get(spanCtx, hash)
+	if err != nil {
+		return err
+	}
+	if reset == nil {
+		return ErrInvalidResetToken
+	}
+
+	user, err := u.repo.GetUserByID(spanCtx, reset.UserID)
+	if errors.Is(err, drivers.ErrUserNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(user.Email, reset.Email) {
+		return ErrInvalidResetToken
+	}
+
+	if err = u.checkNewPassword(user, password); err != nil {
+		return err
+	}
+
+	// only the first of concurrent requests with the same token gets through
+	reset, err = u.resets.Take(spanCtx, hash)
+	if err != nil {
+		return err
+	}
+	if reset == nil {
+		return ErrInvalidResetToken
+	}
+
+	if err = u.setPassword(spanCtx, user, password); err != nil {
+		return err
+	}
+
+	// the link reached the inbox, so the address is confirmed as well
+	if !user.EmailVerified {
+		if err = u.repo.UpdateUserEmailVerified(spanCtx, user.Id, true); err != nil {
+			return err
+		}
+	}
+
+	// whoever can read the inbox owns the account, a lockout would only be in the way
+	if err = u.loginAttempts.Reset(spanCtx, emailAttemptsKey(user.Email)); err != nil {
+		return err
+	}
+
+	u.logger.Info("password reset", zap.Int("user_id", user.Id))
+	u.record(spanCtx, selfEvent(entity.AuditPasswordReset, user, nil))
+
+	return u.revokeUser(spanCtx, user.Id)
+}
+
+// ChangePassword replaces the password of a logged in user after checking the
+// current one. Every session ends, including the one making the request.
+func (u *User) ChangePassword(ctx context.Context, userID int, currentPassword, newPassword string) error {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "change password use case")
+	defer span.Finish()
+
+	user, err := u.repo.GetUserByID(spanCtx, userID)
+	if err != nil {
+		return err
+	}
+
+	if match, _ := u.hasher.Verify(user.Password, currentPassword); !match {
+		return ErrWrongPassword
+	}
+
+	if err = u.checkNewPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	if err = u.setPassword(spanCtx, user, newPassword); err != nil {
+		return err
+	}
+
+	u.logger.Info("password changed", zap.Int("user_id", user.Id))
+	u.record(spanCtx, selfEvent(entity.AuditPasswordChanged, user, nil))
+
+	return u.revokeUser(spanCtx, user.Id)
+}
+
+// checkNewPassword applies the password policy and rejects the current
+// password and the ones kept in the history.
+func (u *User) checkNewPassword(user *entity.User, password string) error {
+	if err := u.passwords.Validate(password); err != nil {
+		return fmt.Errorf("%w: %s", ErrWeakPassword, err)
+	}
+
+	if u.passwords.History() <= 0 {
+		return nil
+	}
+
+	for _, previous := range append([]string{user.Password}, user.PasswordHistory...) {
+		// hashes in an unknown format are skipped, not treated as a match
+		if match, _ := u.hasher.Verify(previous, password); match {
+			return ErrPasswordReused
+		}
+	}
+
+	return nil
+}
+
+// setPassword stores the new password hash and moves the current one into
+// the history, which is capped at the configured length. Reset links sent
+// for the old password stop working.
+func (u *User) setPassword(ctx context.Context, user *entity.User, password string) error {
+	generatedHash, err := u.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	history := []string{}
+	if keep := u.passwords.History(); keep > 0 {
+		history = append([]string{user.Password}, user.PasswordHistory...)
+		if len(history) > keep {
+			history = history[:keep]
+		}
+	}
+
+	if err = u.repo.UpdateUserPassword(ctx, user.Id, generatedHash, history); err != nil {
+		return err
+	}
+
+	return u.resets.RevokeUser(ctx, user.Id)
+}
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// Logout revokes the access token it was called with together with its
+// session, or every session of the user when allSessions is set.
+func (u *User) Logout(ctx context.Context, claims jwt.MapClaims, allSessions bool) error {
+	userID := claimInt(claims, "user_id")
+
+	if allSessions {
+		return u.revokeUser(ctx, userID)
+	}
+
+	err := u.denylist.RevokeToken(ctx, claimString(claims, "jti"), time.Until(claimTime(claims, "exp")))
+	if err != nil {
+		return err
+	}
+
+	if sid := claimString(claims, "sid"); sid != "" {
+		return u.revokeSession(ctx, sid)
+	}
+
+	return nil
+}
+
+// RevokeToken implements RFC 7009 for the client clientID. The hint only
+// decides which token type is tried first, an unknown hint is ignored.
+// Unknown, expired or already revoked tokens are not an error, tokens issued
+// to another client are refused with ErrTokenClientMismatch.
+func (u *User) RevokeToken(ctx context.Context, token, tokenTypeHint, clientID string) error {
+	revokers := []func(context.Context, string, string) (bool, error){u.revokeRefreshToken, u.revokeAccessToken}
+
+	if tokenTypeHint == TokenTypeAccess {
+		revokers[0], revokers[1] = revokers[1], revokers[0]
+	}
+
+	for _, revoke := range revokers {
+		revoked, err := revoke(ctx, token, clientID)
+		if err != nil || revoked {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revokeUser ends every session of the user: all refresh token families are
+// dropped, the session records revoked and access tokens issued until now
+// are denied. The cutoff has a one second resolution, so the sessions are
+// denied by id as well to cover tokens issued in the same second.
+func (u *User) revokeUser(ctx context.Context, userID int) error {
+	families, err := u.refreshTokens.RevokeUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err = u.repo.RevokeUserSessions(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+
+	u.logger.Info("user logged out of all sessions", zap.Int("user_id", userID), zap.Int("sessions", len(families)))
+
+	for _, sid := range families {
+		if err = u.denylist.RevokeSession(ctx, sid, u.accessTokenTTL()); err != nil {
+			return err
+		}
+	}
+
+	return u.denylist.RevokeUser(ctx, userID, u.accessTokenTTL())
+}
+
+func (u *User) revokeAccessToken(ctx context.Context, token, clientID string) (bool, error) {
+	claims := jwt.MapClaims{}
+
+	parsed, err := jwt.ParseWithClaims(token, claims, u.signer.Keyfunc)
+	if err != nil || !parsed.Valid || claimString(claims, "token_use") != tokenUseAccess {
+		return false, nil
+	}
+	if claimString(claims, "client_id") != clientID {
+		return false, ErrTokenClientMismatch
+	}
+
+	return true, u.denylist.RevokeToken(ctx, claimString(claims, "jti"), time.Until(claimTime(claims, "exp")))
+}
+
+func (u *User) revokeRefreshToken(ctx context.Context, token, clientID string) (bool, error) {
+	record, err := u.refreshTokens.Get(ctx, hashToken(token))
+	if err != nil || record == nil {
+		return false, err
+	}
+	if record.ClientID != clientID {
+		return false, ErrTokenClientMismatch
+	}
+
+	return true, u.revokeSession(ctx, record.FamilyID)
+}
+
+// revokeSession drops the refresh token family, marks its session record
+// revoked and denies the access tokens already issued for it.
+func (u *User) revokeSession(ctx context.Context, sid string) error {
+	if err := u.refreshTokens.RevokeFamily(ctx, sid); err != nil {
+		return err
+	}
+
+	err := u.repo.RevokeSession(ctx, sid, time.Now())
+	if err != nil && !errors.Is(err, drivers.ErrSessionNotFound) {
+		return err
+	}
+
+	return u.denylist.RevokeSession(ctx, sid, u.accessTokenTTL())
+}
@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// Sessions lists the active logins of the user, the most recently used first.
+func (u *User) Sessions(ctx context.Context, userID int) ([]*entity.Session, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "sessions use case")
+	defer span.Finish()
+
+	return u.repo.GetSessions(spanCtx, userID)
+}
+
+// EndSession logs the user out of one session. Sessions of other users and
+// ended ones are reported as not found.
+func (u *User) EndSession(ctx context.Context, userID int, sessionID string) error {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "end session use case")
+	defer span.Finish()
+
+	session, err := u.repo.GetSession(spanCtx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID || session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return drivers.ErrSessionNotFound
+	}
+
+	u.logger.Info("session ended", zap.Int("user_id", userID), zap.String("session_id", sessionID))
+
+	return u.revokeSession(spanCtx, sessionID)
+}
+
+// EndSessions logs the user out everywhere.
+func (u *User) EndSessions(ctx context.Context, userID int) error {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "end sessions use case")
+	defer span.Finish()
+
+	if _, err := u.repo.GetUserByID(spanCtx, userID); err != nil {
+		return err
+	}
+
+	return u.revokeUser(spanCtx, userID)
+}
+
+// startSession records the login that starts the refresh token family.
+func (u *User) startSession(ctx context.Context, user *entity.User, token *entity.RefreshToken, origin entity.Origin,
+	now time.Time) error {
+	err := u.repo.CreateSession(ctx, &entity.Session{
+		ID:         token.FamilyID,
+		UserID:     token.UserID,
+		ClientID:   token.ClientID,
+		Device:     deviceName(origin.UserAgent),
+		UserAgent:  origin.UserAgent,
+		IP:         origin.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  token.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	event := selfEvent(entity.AuditLoginSucceeded, user, sessionDetails(token))
+	event.IP, event.UserAgent = origin.IP, origin.UserAgent
+	u.record(ctx, event)
+
+	return nil
+}
+
+// sessionDetails describes the session of a token in audit events.
+func sessionDetails(token *entity.RefreshToken) map[string]string {
+	details := map[string]string{"session_id": token.FamilyID}
+	if token.ClientID != "" {
+		details["client_id"] = token.ClientID
+	}
+	return details
+}
+
+// touchSession moves the last-seen time and expiry of the session on a
+// refresh. Families started before sessions were recorded have none.
+func (u *User) touchSession(ctx context.Context, token *entity.RefreshToken, now time.Time) error {
+	err := u.repo.TouchSession(ctx, token.FamilyID, now, token.ExpiresAt)
+	if errors.Is(err, drivers.ErrSessionNotFound) {
+		return nil
+	}
+	return err
+}
+
+// browsers and platforms in the order they are looked for, the first match
+// wins: Edge and Opera also claim to be Chrome, Chrome claims to be Safari
+var (
+	userAgentBrowsers = [][2]string{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"},
+		{"CriOS/", "Chrome"}, {"Safari/", "Safari"}, {"curl/", "curl"},
+	}
+	userAgentPlatforms = [][2]string{
+		{"iPhone", "iPhone"}, {"iPad", "iPad"}, {"Android", "Android"}, {"Windows", "Windows"},
+		{"Mac OS X", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	}
+)
+
+// deviceName makes a short label like "Chrome on Windows" from a user agent.
+// Unknown parts are left out, an unknown user agent gives "".
+func deviceName(userAgent string) string {
+	find := func(table [][2]string) string {
+		for _, entry := range table {
+			if strings.Contains(userAgent, entry[0]) {
+				return entry[1]
+			}
+		}
+		return ""
+	}
+
+	browser, platform := find(userAgentBrowsers), find(userAgentPlatforms)
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	default:
+		return platform
+	}
+}
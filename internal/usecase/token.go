@@ -0,0 +1,260 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/golang-jwt/jwt"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+const refreshTokenBytes = 32
+
+// tokenUseAccess marks the tokens that grant access. Every other token signed
+// with the same key, like ID tokens or email verification links, lacks it.
+const tokenUseAccess = "access"
+
+// Refresh exchanges a refresh token from the password login for a new token
+// pair. The presented token is spent; presenting it again revokes every token
+// of its family.
+func (u *User) Refresh(ctx context.Context, refreshToken string) (*dto.LoginResponse, error) {
+	return u.refresh(ctx, refreshToken, "")
+}
+
+// refresh rotates a refresh token issued to clientID, an empty client id
+// stands for the password login.
+func (u *User) refresh(ctx context.Context, refreshToken, clientID string) (*dto.LoginResponse, error) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "refresh use case")
+	defer span.Finish()
+
+	hash := hashToken(refreshToken)
+
+	record, err := u.refreshTokens.Get(spanCtx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.ClientID != clientID {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	firstUse, err := u.refreshTokens.MarkUsed(spanCtx, hash, time.Until(record.ExpiresAt))
+	if err != nil {
+		return nil, err
+	}
+	if !firstUse {
+		u.logger.Warn("refresh token reuse detected, revoking token family",
+			zap.Int("user_id", record.UserID), zap.String("family_id", record.FamilyID))
+
+		if err = u.revokeSession(spanCtx, record.FamilyID); err != nil {
+			return nil, err
+		}
+
+		u.record(spanCtx, &entity.AuditEvent{
+			Type:     entity.AuditTokenReused,
+			TargetID: record.UserID,
+			Details:  map[string]string{"session_id": record.FamilyID},
+		})
+
+		return nil, ErrRefreshTokenReused
+	}
+
+	user, err := u.repo.GetUserByID(spanCtx, record.UserID)
+	if err != nil {
+		return nil, err
+	}
+	//не изменились ли роли
+
+	tokens, err := u.issueTokens(spanCtx, user, record, entity.Origin{})
+	if err != nil {
+		return nil, err
+	}
+
+	u.record(spanCtx, selfEvent(entity.AuditTokenRefreshed, user, sessionDetails(record)))
+
+	return tokens, nil
+}
+
+// VerifyAccessToken checks the signature, expiry and revocation state of an access token.
+func (u *User) VerifyAccessToken(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, u.signer.Keyfunc)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidAccessToken
+	}
+
+	if claimString(claims, "token_use") != tokenUseAccess {
+		return nil, ErrInvalidAccessToken
+	}
+
+	revoked, err := u.denylist.IsRevoked(ctx,
+		claimString(claims, "jti"),
+		claimString(claims, "sid"),
+		claimInt(claims, "user_id"),
+		claimTime(claims, "iat"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// issueTokens signs an access token and stores a new refresh token for the
+// session. A nil session or one without a family id starts a new family and
+// records the login from origin; the family id doubles as the session id
+// (sid) of the access token.
+func (u *User) issueTokens(ctx context.Context, user *entity.User, session *entity.RefreshToken,
+	origin entity.Origin) (*dto.LoginResponse, error) {
+	now := time.Now()
+
+	next := entity.RefreshToken{AuthTime: now}
+	if session != nil {
+		next = *session
+	}
+	next.UserID = user.Id
+	next.ExpiresAt = now.Add(ttl(u.cfg.RefreshTokenTTL, RefreshTokenTTL))
+
+	var err error
+	if next.FamilyID == "" {
+		next.FamilyID, err = randomString(16)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = u.startSession(ctx, user, &next, origin, now); err != nil {
+			return nil, err
+		}
+	} else if err = u.touchSession(ctx, &next, now); err != nil {
+		return nil, err
+	}
+
+	jti, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{
+		"sub":         strconv.Itoa(user.Id),
+		"user_id":     user.Id,
+		"email":       user.Email,
+		"name":        user.Name,
+		"roles":       user.Roles,
+		"permissions": user.EffectivePermissions(),
+		"jti":         jti,
+		"sid":         next.FamilyID,
+		"token_use":   tokenUseAccess,
+		"iat":         now.Unix(),
+		"auth_time":   next.AuthTime.Unix(),
+		"exp":         now.Add(u.accessTokenTTL()).Unix(),
+	}
+	if u.cfg.Issuer != "" {
+		claims["iss"] = u.cfg.Issuer
+	}
+	if next.Scope != "" {
+		claims["scope"] = next.Scope
+	}
+	if next.ClientID != "" {
+		claims["client_id"] = next.ClientID
+	}
+
+	accessTokenString, err := u.signer.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokenString, err := randomString(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.refreshTokens.Save(ctx, hashToken(refreshTokenString), &next)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginResponse{
+		Name:         user.Name,
+		Email:        user.Email,
+		AccessToken:  accessTokenString,
+		RefreshToken: refreshTokenString,
+	}, nil
+}
+
+// issueClientToken signs an access token for a client acting on its own
+// behalf. There is no user and no refresh token.
+func (u *User) issueClientToken(client *entity.Client, scope string) (string, error) {
+	now := time.Now()
+
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"sub":       client.ClientID,
+		"client_id": client.ClientID,
+		"gty":       GrantTypeClientCredentials,
+		"token_use": tokenUseAccess,
+		"scope":     scope,
+		"jti":       jti,
+		"iat":       now.Unix(),
+		"exp":       now.Add(u.accessTokenTTL()).Unix(),
+	}
+	if u.cfg.Issuer != "" {
+		claims["iss"] = u.cfg.Issuer
+	}
+
+	return u.signer.Sign(claims)
+}
+
+func (u *User) accessTokenTTL() time.Duration {
+	return ttl(u.cfg.AccessTokenTTL, AccessTokenTTL)
+}
+
+// ttl converts a lifetime from config in seconds, falling back to the default.
+func ttl(seconds, fallback int64) time.Duration {
+	if seconds <= 0 {
+		seconds = fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// claimInt reads a numeric claim, encoding/json decodes numbers as float64.
+func claimInt(claims jwt.MapClaims, name string) int {
+	value, _ := claims[name].(float64)
+	return int(value)
+}
+
+func claimTime(claims jwt.MapClaims, name string) time.Time {
+	value, _ := claims[name].(float64)
+	return time.Unix(int64(value), 0)
+}
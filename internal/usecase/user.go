@@ -4,16 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/golang-jwt/jwt"
-	"github.com/jackc/pgx/v4"
 	"github.com/madyar997/sso-jcode/config"
 	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
 	"github.com/madyar997/sso-jcode/internal/database/drivers"
 	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/pkg/audit"
+	"github.com/madyar997/sso-jcode/pkg/cache"
+	"github.com/madyar997/sso-jcode/pkg/hasher"
 	"github.com/madyar997/sso-jcode/pkg/logger"
+	"github.com/madyar997/sso-jcode/pkg/mailer"
+	"github.com/madyar997/sso-jcode/pkg/policy"
+	"github.com/madyar997/sso-jcode/pkg/signer"
 	"github.com/opentracing/opentracing-go"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"time"
 )
 
@@ -21,13 +25,45 @@ const AccessTokenTTL = 900
 const RefreshTokenTTL = 1800
 
 type User struct {
-	cfg    *config.Config
-	repo   drivers.DataStore
-	logger *logger.Logger
+	cfg           *config.Config
+	repo          drivers.DataStore
+	logger        *logger.Logger
+	signer        signer.Signer
+	users         cache.User
+	refreshTokens cache.RefreshToken
+	denylist      cache.Denylist
+	mfa           cache.MFA
+	verifications cache.EmailVerification
+	resets        cache.PasswordReset
+	mailer        mailer.Mailer
+	passwords     *policy.Password
+	hasher        hasher.PasswordHasher
+	loginAttempts cache.LoginAttempts
+	auditor       audit.Recorder
 }
 
-func NewUser(repo drivers.DataStore, cfg *config.Config, logger *logger.Logger) *User {
-	return &User{repo: repo, cfg: cfg, logger: logger}
+func NewUser(repo drivers.DataStore, cfg *config.Config, logger *logger.Logger, jwtSigner signer.Signer,
+	users cache.User, refreshTokens cache.RefreshToken, denylist cache.Denylist, mfa cache.MFA,
+	verifications cache.EmailVerification, resets cache.PasswordReset, mailer mailer.Mailer,
+	passwords *policy.Password, hasher hasher.PasswordHasher, loginAttempts cache.LoginAttempts,
+	auditor audit.Recorder) *User {
+	return &User{
+		repo:          repo,
+		cfg:           cfg,
+		logger:        logger,
+		signer:        jwtSigner,
+		users:         users,
+		refreshTokens: refreshTokens,
+		denylist:      denylist,
+		mfa:           mfa,
+		verifications: verifications,
+		resets:        resets,
+		mailer:        mailer,
+		passwords:     passwords,
+		hasher:        hasher,
+		loginAttempts: loginAttempts,
+		auditor:       auditor,
+	}
 }
 
 func (u *User) GetUserByID(ctx context.Context, id int) (*entity.User, error) {
@@ -41,82 +77,243 @@ func (u *User) GetUserByEmail(ctx context.Context, email string) (*entity.User,
 	return u.repo.GetUserByEmail(ctx, email)
 }
 
-func (u *User) Users(ctx context.Context) ([]*entity.User, error) {
-	return u.repo.GetUsers(ctx)
+// CreateUser creates an account on behalf of an admin. The password goes
+// through the same policy and hashing as on registration.
+func (u *User) CreateUser(ctx context.Context, req *dto.CreateUserRequest) (int, error) {
+	roles := req.Roles
+	if len(roles) == 0 {
+		roles = []string{entity.RoleUser}
+	}
+
+	if err := validateRoles(roles); err != nil {
+		return 0, err
+	}
+
+	if err := u.passwords.Validate(req.Password); err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrWeakPassword, err)
+	}
+
+	generatedHash, err := u.hasher.Hash(req.Password)
+	if err != nil {
+		return 0, err
+	}
+
+	user := &entity.User{
+		Name:     req.Name,
+		Email:    req.Email,
+		Age:      req.Age,
+		Password: generatedHash,
+		Roles:    roles,
+	}
+
+	id, err := u.repo.CreateUser(ctx, user, userEvent(entity.EventUserCreated, user))
+	if err != nil {
+		return 0, err
+	}
+
+	u.record(ctx, &entity.AuditEvent{
+		Type:        entity.AuditUserCreated,
+		TargetID:    id,
+		TargetEmail: user.Email,
+		Details:     map[string]string{"roles": strings.Join(user.Roles, ",")},
+	})
+
+	return id, nil
+}
+
+// SetRoles replaces the roles and directly assigned permissions of a user.
+// Tokens already issued carry the old roles, so every session of the user is
+// revoked and the new roles apply from the next login.
+func (u *User) SetRoles(ctx context.Context, id int, roles, permissions []string) error {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "set user roles use case")
+	defer span.Finish()
+
+	if err := validateRoles(roles); err != nil {
+		return err
+	}
+
+	if err := u.repo.UpdateUserRoles(spanCtx, id, roles, permissions); err != nil {
+		return err
+	}
+
+	u.logger.Info("user roles changed", zap.Int("user_id", id), zap.Strings("roles", roles))
+
+	u.record(spanCtx, &entity.AuditEvent{
+		Type:     entity.AuditRolesChanged,
+		TargetID: id,
+		Details: map[string]string{
+			"roles":       strings.Join(roles, ","),
+			"permissions": strings.Join(permissions, ","),
+		},
+	})
+
+	return u.revokeUser(spanCtx, id)
+}
+
+// defaultRoles returns the roles a self-registered account starts with.
+func (u *User) defaultRoles(email string) []string {
+	for _, adminEmail := range u.cfg.AdminEmails {
+		if strings.EqualFold(adminEmail, email) {
+			return []string{entity.RoleAdmin, entity.RoleUser}
+		}
+	}
+	return []string{entity.RoleUser}
+}
+
+// userEvent builds the outbox event about user, the payload is what other
+// services may know about the account and never carries secrets.
+func userEvent(eventType string, user *entity.User) *entity.OutboxEvent {
+	now := time.Now().UTC()
+
+	return &entity.OutboxEvent{
+		Type:        eventType,
+		AggregateID: user.Id,
+		Payload: map[string]interface{}{
+			"email":          user.Email,
+			"name":           user.Name,
+			"roles":          user.Roles,
+			"email_verified": user.EmailVerified,
+		},
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
 }
 
-func (u *User) CreateUser(ctx context.Context, user *entity.User) (int, error) {
-	return u.repo.CreateUser(ctx, user)
+func validateRoles(roles []string) error {
+	for _, role := range roles {
+		if _, ok := entity.RolePermissions[role]; !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownRole, role)
+		}
+	}
+	return nil
 }
 
+// Register creates the account and mails the verification link. A failed
+// send does not undo the registration, the link can be requested again.
 func (u *User) Register(ctx context.Context, email, password string) error {
-	generatedHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err := u.passwords.Validate(password); err != nil {
+		return fmt.Errorf("%w: %s", ErrWeakPassword, err)
+	}
+
+	generatedHash, err := u.hasher.Hash(password)
 	if err != nil {
 		return err
 	}
 
-	_, err = u.repo.CreateUser(ctx, &entity.User{
+	user := &entity.User{
 		Email:    email,
-		Password: string(generatedHash),
-	})
+		Password: generatedHash,
+		Roles:    u.defaultRoles(email),
+	}
+
+	user.Id, err = u.repo.CreateUser(ctx, user, userEvent(entity.EventUserRegistered, user))
 	if err != nil {
 		return err
 	}
 
+	u.record(ctx, selfEvent(entity.AuditUserRegistered, user, nil))
+
+	if err = u.sendVerification(ctx, user); err != nil {
+		u.logger.Error("could not send verification email", zap.Int("user_id", user.Id), zap.Error(err))
+	}
+
 	return nil
 }
 
-func (u *User) Login(ctx context.Context, email, password string) (*dto.LoginResponse, error) {
+func (u *User) Login(ctx context.Context, email, password string, origin entity.Origin) (*dto.LoginResponse, error) {
 	span, spanCtx := opentracing.StartSpanFromContext(ctx, "login use case")
 	defer span.Finish()
 
-	user, err := u.repo.GetUserByEmail(spanCtx, email)
+	user, err := u.Authenticate(spanCtx, email, password, origin.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MFAEnabled {
+		return u.mfaChallenge(spanCtx, user)
+	}
+
+	u.logger.Info("generating access and refresh tokens ...")
+
+	return u.issueTokens(spanCtx, user, nil, origin)
+}
+
+// Authenticate checks the email and password pair without issuing tokens.
+// Failures are counted per email and client ip, see checkLoginAllowed. For a
+// user with MFA enabled the count is kept until the second factor is passed.
+func (u *User) Authenticate(ctx context.Context, email, password, clientIP string) (*entity.User, error) {
+	if err := u.checkLoginAllowed(ctx, email, clientIP); err != nil {
+		var retryErr *RetryAfterError
+		if errors.As(err, &retryErr) {
+			u.recordLoginFailed(ctx, email, nil, loginFailedThrottled)
+		}
+		return nil, err
+	}
+
+	user, err := u.repo.GetUserByEmail(ctx, email)
 	switch {
 	case err == nil:
-	case errors.Is(err, pgx.ErrNoRows):
+	case errors.Is(err, drivers.ErrUserNotFound):
 		u.logger.Warn("user not found", zap.Error(err))
-		return nil, errors.New("user is not exist")
+		u.recordLoginFailed(ctx, email, nil, loginFailedUnknownEmail)
+		if err = u.loginFailed(ctx, email, clientIP); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
 	default:
 		return nil, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	match, err := u.hasher.Verify(user.Password, password)
 	if err != nil {
-		u.logger.Error("passwords not match", zap.Error(err))
-		return nil, errors.New(fmt.Sprintf("passwords do not match %v", err))
+		u.logger.Warn("unreadable password hash", zap.Int("user_id", user.Id), zap.Error(err))
 	}
-
-	u.logger.Info("generating access and refresh tokens ...")
-	accessTokenClaims := jwt.MapClaims{
-		"user_id": user.Id,
-		"email":   user.Email,
-		"name":    user.Name,
-		"exp":     time.Now().Add(time.Hour * 1).Unix(),
+	if !match {
+		u.logger.Error("passwords not match", zap.Int("user_id", user.Id))
+		u.recordLoginFailed(ctx, email, user, loginFailedWrongPassword)
+		if err = u.loginFailed(ctx, email, clientIP); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), accessTokenClaims)
+	if u.hasher.NeedsRehash(user.Password) {
+		u.rehash(ctx, user, password)
+	}
 
-	accessTokenString, err := accessToken.SignedString([]byte(u.cfg.SecretKey))
-	if err != nil {
-		return nil, err
+	// checked after the password so the answer does not reveal the account
+	if u.cfg.RequireEmailVerification && !user.EmailVerified {
+		u.recordLoginFailed(ctx, email, user, loginFailedEmailNotVerified)
+		return nil, ErrEmailNotVerified
 	}
 
-	refreshTokenClaims := jwt.MapClaims{
-		"user_id": user.Id,
-		"exp":     time.Now().Add(time.Hour * 1),
+	// with MFA the login is not complete yet, LoginMFA forgets the failures
+	if !user.MFAEnabled {
+		if err = u.loginSucceeded(ctx, email); err != nil {
+			return nil, err
+		}
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), refreshTokenClaims)
+	return user, nil
+}
+
+// rehash replaces a hash made with outdated settings while the plain password
+// is at hand. A failure only delays the upgrade to the next login.
+func (u *User) rehash(ctx context.Context, user *entity.User, password string) {
+	history := user.PasswordHistory
+	if history == nil {
+		history = []string{}
+	}
 
-	resfreshTokenString, err := refreshToken.SignedString([]byte(u.cfg.SecretKey))
+	generatedHash, err := u.hasher.Hash(password)
+	if err == nil {
+		err = u.repo.UpdateUserPassword(ctx, user.Id, generatedHash, history)
+	}
 	if err != nil {
-		return nil, err
+		u.logger.Error("could not rehash password", zap.Int("user_id", user.Id), zap.Error(err))
+		return
 	}
 
-	return &dto.LoginResponse{
-		Name:         user.Name,
-		Email:        user.Email,
-		AccessToken:  accessTokenString,
-		RefreshToken: resfreshTokenString,
-	}, nil
+	user.Password = generatedHash
+	u.logger.Info("password rehashed", zap.Int("user_id", user.Id))
 }
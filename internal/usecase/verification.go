@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/pkg/mailer"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const EmailVerificationTTL = 86400
+
+const purposeEmailVerification = "email_verification"
+
+// VerifyEmail marks the email of the token's user as verified. Every token
+// works once and only for the address it was issued for.
+func (u *User) VerifyEmail(ctx context.Context, token string) error {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "verify email use case")
+	defer span.Finish()
+
+	claims := jwt.MapClaims{}
+
+	parsed, err := jwt.ParseWithClaims(token, claims, u.signer.Keyfunc)
+	if err != nil || !parsed.Valid || claimString(claims, "purpose") != purposeEmailVerification {
+		return ErrInvalidVerification
+	}
+
+	firstUse, err := u.verifications.MarkUsed(spanCtx, claimString(claims, "jti"), time.Until(claimTime(claims, "exp")))
+	if err != nil {
+		return err
+	}
+	if !firstUse {
+		return ErrInvalidVerification
+	}
+
+	user, err := u.repo.GetUserByID(spanCtx, claimInt(claims, "user_id"))
+	if errors.Is(err, drivers.ErrUserNotFound) {
+		return ErrInvalidVerification
+	}
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(user.Email, claimString(claims, "email")) {
+		return ErrInvalidVerification
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	if err = u.repo.UpdateUserEmailVerified(spanCtx, user.Id, true); err != nil {
+		return err
+	}
+
+	u.logger.Info("email verified", zap.Int("user_id", user.Id))
+
+	return nil
+}
+
+// ResendVerification mails a new verification link. Unknown and already
+// verified addresses are ignored so the caller learns nothing about accounts.
+func (u *User) ResendVerification(ctx context.Context, email string) error {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "resend verification use case")
+	defer span.Finish()
+
+	user, err := u.repo.GetUserByEmail(spanCtx, email)
+	if errors.Is(err, drivers.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	return u.sendVerification(spanCtx, user)
+}
+
+func (u *User) sendVerification(ctx context.Context, user *entity.User) error {
+	token, err := u.verificationToken(user)
+	if err != nil {
+		return err
+	}
+
+	link := token
+	if u.cfg.EmailVerificationURL != "" {
+		link = u.cfg.EmailVerificationURL + "?" + url.Values{"token": {token}}.Encode()
+	}
+
+	return u.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Open the link below to confirm your email address:\n\n%s\n\n"+
+			"The link expires in %s. If you did not create an account, ignore this email.\n",
+			link, ttl(u.cfg.EmailVerificationTTL, EmailVerificationTTL)),
+	})
+}
+
+// verificationToken signs a token bound to the user id and email. Without a
+// token_use claim it is not accepted as an access token.
+func (u *User) verificationToken(user *entity.User) (string, error) {
+	now := time.Now()
+
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"sub":     strconv.Itoa(user.Id),
+		"user_id": user.Id,
+		"email":   user.Email,
+		"purpose": purposeEmailVerification,
+		"jti":     jti,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl(u.cfg.EmailVerificationTTL, EmailVerificationTTL)).Unix(),
+	}
+	if u.cfg.Issuer != "" {
+		claims["iss"] = u.cfg.Issuer
+	}
+
+	return u.signer.Sign(claims)
+}
@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/madyar997/sso-jcode/internal/controller/http/v1/dto"
+	"github.com/madyar997/sso-jcode/internal/database/drivers"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"github.com/madyar997/sso-jcode/pkg/webhook"
+	"go.uber.org/zap"
+	"net/url"
+	"time"
+)
+
+const (
+	DeliveryPageSize    = 50
+	DeliveryMaxPageSize = 500
+
+	webhookSecretBytes  = 32
+	webhookSecretPrefix = "whsec_"
+)
+
+// event types an endpoint can subscribe to
+var webhookEventTypes = []string{
+	entity.WebhookAllEvents,
+	entity.EventUserRegistered,
+	entity.EventUserCreated,
+	entity.EventUserEmailChanged,
+	entity.EventUserDeleted,
+	entity.EventUserRestored,
+}
+
+type Webhook struct {
+	repo       drivers.WebhookRepo
+	dispatcher *webhook.Dispatcher
+	logger     *logger.Logger
+}
+
+func NewWebhook(repo drivers.WebhookRepo, dispatcher *webhook.Dispatcher, logger *logger.Logger) *Webhook {
+	return &Webhook{repo: repo, dispatcher: dispatcher, logger: logger}
+}
+
+func (w *Webhook) Webhooks(ctx context.Context) ([]*entity.WebhookEndpoint, error) {
+	return w.repo.GetWebhookEndpoints(ctx)
+}
+
+func (w *Webhook) GetWebhook(ctx context.Context, id int) (*entity.WebhookEndpoint, error) {
+	return w.repo.GetWebhookEndpoint(ctx, id)
+}
+
+// CreateWebhook registers an endpoint with a new signing secret.
+func (w *Webhook) CreateWebhook(ctx context.Context, req *dto.CreateWebhookRequest) (*entity.WebhookEndpoint, error) {
+	now := time.Now()
+
+	endpoint := &entity.WebhookEndpoint{
+		URL:         req.URL,
+		Description: req.Description,
+		EventTypes:  req.EventTypes,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := validateWebhook(endpoint); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if endpoint.Secret, err = newWebhookSecret(); err != nil {
+		return nil, err
+	}
+
+	if _, err = w.repo.CreateWebhookEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+
+	w.logger.Info("webhook endpoint registered", zap.Int("webhook_id", endpoint.Id), zap.String("url", endpoint.URL))
+
+	return endpoint, nil
+}
+
+func (w *Webhook) UpdateWebhook(ctx context.Context, id int, req *dto.UpdateWebhookRequest) (*entity.WebhookEndpoint, error) {
+	endpoint, err := w.repo.GetWebhookEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.URL = req.URL
+	endpoint.Description = req.Description
+	endpoint.EventTypes = req.EventTypes
+	if req.Enabled != nil {
+		endpoint.Enabled = *req.Enabled
+	}
+	endpoint.UpdatedAt = time.Now()
+
+	if err = validateWebhook(endpoint); err != nil {
+		return nil, err
+	}
+
+	return endpoint, w.repo.UpdateWebhookEndpoint(ctx, endpoint)
+}
+
+func (w *Webhook) DeleteWebhook(ctx context.Context, id int) error {
+	return w.repo.DeleteWebhookEndpoint(ctx, id)
+}
+
+// RotateWebhookSecret replaces the signing secret, deliveries sent from now
+// on are signed with the new one.
+func (w *Webhook) RotateWebhookSecret(ctx context.Context, id int) (*entity.WebhookEndpoint, error) {
+	endpoint, err := w.repo.GetWebhookEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint.Secret, err = newWebhookSecret(); err != nil {
+		return nil, err
+	}
+	endpoint.UpdatedAt = time.Now()
+
+	return endpoint, w.repo.UpdateWebhookEndpoint(ctx, endpoint)
+}
+
+// Deliveries returns a page of the deliveries to the endpoint, newest first,
+// and the number of deliveries matching the filters. The limit of req is set
+// to the page size used.
+func (w *Webhook) Deliveries(ctx context.Context, id int, req *dto.WebhookDeliveriesRequest) ([]*entity.WebhookDelivery, int64, error) {
+	if _, err := w.repo.GetWebhookEndpoint(ctx, id); err != nil {
+		return nil, 0, err
+	}
+
+	switch {
+	case req.Limit <= 0:
+		req.Limit = DeliveryPageSize
+	case req.Limit > DeliveryMaxPageSize:
+		req.Limit = DeliveryMaxPageSize
+	}
+
+	return w.repo.GetWebhookDeliveries(ctx, &drivers.WebhookDeliveryQuery{
+		EndpointID: id,
+		Status:     req.Status,
+		Limit:      req.Limit,
+		Offset:     req.Offset,
+	})
+}
+
+// GetDelivery returns a delivery to the endpoint, deliveries to other
+// endpoints are not found.
+func (w *Webhook) GetDelivery(ctx context.Context, id, deliveryID int) (*entity.WebhookDelivery, error) {
+	delivery, err := w.repo.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.EndpointID != id {
+		return nil, drivers.ErrDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+// ReplayDelivery sends a delivery again right away, whatever its state, and
+// returns it with the outcome. A failed replay of a delivery that has
+// retries left is retried like any other attempt.
+func (w *Webhook) ReplayDelivery(ctx context.Context, id, deliveryID int) (*entity.WebhookDelivery, error) {
+	delivery, err := w.GetDelivery(ctx, id, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	w.logger.Info("replaying webhook delivery", zap.Int("webhook_id", id), zap.Int("delivery_id", deliveryID))
+
+	return delivery, w.dispatcher.Deliver(ctx, delivery)
+}
+
+func validateWebhook(endpoint *entity.WebhookEndpoint) error {
+	u, err := url.Parse(endpoint.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https url", ErrInvalidWebhook)
+	}
+
+	for _, eventType := range endpoint.EventTypes {
+		if !contains(webhookEventTypes, eventType) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, eventType)
+		}
+	}
+
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	secret, err := randomString(webhookSecretBytes)
+	if err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + secret, nil
+}
@@ -0,0 +1 @@
+drop table if exists clients;
\ No newline at end of file
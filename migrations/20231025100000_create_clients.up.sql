@@ -0,0 +1,10 @@
+create table clients (
+    id serial primary key,
+    client_id varchar not null unique,
+    secret_hash varchar not null default '',
+    name varchar,
+    redirect_uris jsonb not null default '[]',
+    scopes jsonb not null default '[]',
+    grant_types jsonb not null default '[]',
+    created_at timestamp not null default now()
+);
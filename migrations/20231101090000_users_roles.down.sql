@@ -0,0 +1,3 @@
+alter table users
+    drop column if exists roles,
+    drop column if exists permissions;
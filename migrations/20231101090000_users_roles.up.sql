@@ -0,0 +1,3 @@
+alter table users
+    add column if not exists roles jsonb not null default '["user"]',
+    add column if not exists permissions jsonb not null default '[]';
@@ -0,0 +1,4 @@
+alter table users
+    drop column if exists totp_secret,
+    drop column if exists mfa_enabled,
+    drop column if exists recovery_codes;
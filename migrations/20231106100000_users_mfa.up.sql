@@ -0,0 +1,4 @@
+alter table users
+    add column if not exists totp_secret varchar not null default '',
+    add column if not exists mfa_enabled boolean not null default false,
+    add column if not exists recovery_codes jsonb not null default '[]';
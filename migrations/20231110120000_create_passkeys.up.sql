@@ -0,0 +1,17 @@
+create table passkeys (
+    id serial primary key,
+    user_id int not null references users (id) on delete cascade,
+    credential_id varchar not null unique,
+    name varchar not null default '',
+    public_key bytea not null,
+    attestation_type varchar not null default '',
+    transports jsonb not null default '[]',
+    aaguid bytea,
+    sign_count bigint not null default 0,
+    backup_eligible boolean not null default false,
+    backup_state boolean not null default false,
+    created_at timestamp not null default now(),
+    last_used_at timestamp
+);
+
+create index passkeys_user_id_idx on passkeys (user_id);
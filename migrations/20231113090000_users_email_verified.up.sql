@@ -0,0 +1,5 @@
+alter table users
+    add column if not exists email_verified boolean not null default false;
+
+-- accounts created before verification existed are trusted
+update users set email_verified = true;
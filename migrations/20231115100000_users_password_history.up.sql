@@ -0,0 +1,2 @@
+alter table users
+    add column if not exists password_history jsonb not null default '[]';
@@ -0,0 +1,14 @@
+create table sessions (
+    id varchar primary key,
+    user_id int not null references users (id) on delete cascade,
+    client_id varchar not null default '',
+    device varchar not null default '',
+    user_agent varchar not null default '',
+    ip varchar not null default '',
+    created_at timestamp not null default now(),
+    last_seen_at timestamp not null default now(),
+    expires_at timestamp not null,
+    revoked_at timestamp
+);
+
+create index sessions_user_id_idx on sessions (user_id);
@@ -0,0 +1,21 @@
+create table audit_events (
+    id serial primary key,
+    type varchar not null,
+    actor_id int not null default 0,
+    actor_email varchar not null default '',
+    target_id int not null default 0,
+    target_email varchar not null default '',
+    ip varchar not null default '',
+    user_agent varchar not null default '',
+    trace_id varchar not null default '',
+    details jsonb not null default '{}',
+    created_at timestamp not null default now()
+);
+
+create index audit_events_created_at_idx on audit_events (created_at);
+create index audit_events_actor_id_idx on audit_events (actor_id);
+create index audit_events_target_id_idx on audit_events (target_id);
+
+-- the trail is append-only
+create rule audit_events_no_update as on update to audit_events do instead nothing;
+create rule audit_events_no_delete as on delete to audit_events do instead nothing;
@@ -0,0 +1,15 @@
+create table outbox_events (
+    id serial primary key,
+    type varchar not null,
+    aggregate_id int not null,
+    payload jsonb not null default '{}',
+    attempts int not null default 0,
+    last_error varchar not null default '',
+    next_attempt_at timestamp not null default now(),
+    published_at timestamp,
+    created_at timestamp not null default now()
+);
+
+-- the relay only ever scans pending events
+create index outbox_events_pending_idx on outbox_events (next_attempt_at) where published_at is null;
+create index outbox_events_published_at_idx on outbox_events (published_at) where published_at is not null;
@@ -0,0 +1,31 @@
+create table webhook_endpoints (
+    id serial primary key,
+    url varchar not null,
+    description varchar not null default '',
+    event_types jsonb not null default '[]',
+    secret varchar not null,
+    enabled boolean not null default true,
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now()
+);
+
+create table webhook_deliveries (
+    id serial primary key,
+    endpoint_id int not null references webhook_endpoints (id) on delete cascade,
+    event_id int not null,
+    event_type varchar not null,
+    payload text not null,
+    status varchar not null default 'pending',
+    attempts int not null default 0,
+    response_status int not null default 0,
+    response_body text not null default '',
+    last_error varchar not null default '',
+    next_attempt_at timestamp not null default now(),
+    created_at timestamp not null default now(),
+    delivered_at timestamp
+);
+
+-- an event redelivered by the outbox relay is sent to an endpoint only once
+create unique index webhook_deliveries_endpoint_event_idx on webhook_deliveries (endpoint_id, event_id);
+create index webhook_deliveries_pending_idx on webhook_deliveries (next_attempt_at) where status = 'pending';
+create index webhook_deliveries_created_at_idx on webhook_deliveries (endpoint_id, created_at);
@@ -0,0 +1,3 @@
+-- soft-deleted accounts keep their email reserved so they can be restored
+alter table users
+    add column if not exists deleted_at timestamp;
@@ -0,0 +1,4 @@
+drop index if exists users_created_at_idx;
+
+alter table users
+    drop column if exists created_at;
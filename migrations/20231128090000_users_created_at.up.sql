@@ -0,0 +1,5 @@
+alter table users
+    add column if not exists created_at timestamp not null default now();
+
+-- the admin listing filters and pages by creation date
+create index if not exists users_created_at_idx on users (created_at, id) where deleted_at is null;
@@ -0,0 +1,141 @@
+// Package audit writes the audit trail of security relevant events to one or
+// more sinks.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/madyar997/sso-jcode/config"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+	"os"
+	"time"
+)
+
+const (
+	SinkDatastore = "datastore"
+	SinkFile      = "file"
+	SinkStdout    = "stdout"
+)
+
+// Recorder appends events to the audit trail.
+type Recorder interface {
+	// Record completes the event with the request metadata of ctx and
+	// writes it to every sink.
+	Record(ctx context.Context, event *entity.AuditEvent) error
+}
+
+// Sink stores complete events.
+type Sink interface {
+	Write(ctx context.Context, event *entity.AuditEvent) error
+}
+
+// Store is the datastore part the datastore sink needs.
+type Store interface {
+	CreateAuditEvent(ctx context.Context, event *entity.AuditEvent) error
+}
+
+type Auditor struct {
+	sinks []Sink
+	now   func() time.Time
+}
+
+func NewAuditor(sinks ...Sink) *Auditor {
+	return &Auditor{sinks: sinks, now: time.Now}
+}
+
+// New builds an auditor with the sinks listed in cfg. No sinks make a
+// recorder that drops every event.
+func New(cfg config.Audit, store Store) (*Auditor, error) {
+	sinks := make([]Sink, 0, len(cfg.Sinks))
+
+	for _, name := range cfg.Sinks {
+		switch name {
+		case SinkDatastore:
+			sinks = append(sinks, NewDatastore(store))
+		case SinkFile:
+			file, err := NewFile(cfg.FilePath)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, file)
+		case SinkStdout:
+			sinks = append(sinks, NewWriter(os.Stdout))
+		default:
+			return nil, fmt.Errorf("unknown audit sink %q", name)
+		}
+	}
+
+	return NewAuditor(sinks...), nil
+}
+
+func (a *Auditor) Record(ctx context.Context, event *entity.AuditEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = a.now().UTC()
+	}
+
+	if origin, ok := ctx.Value(originKey{}).(entity.Origin); ok {
+		if event.IP == "" {
+			event.IP = origin.IP
+		}
+		if event.UserAgent == "" {
+			event.UserAgent = origin.UserAgent
+		}
+	}
+
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok && event.ActorID == 0 {
+		event.ActorID, event.ActorEmail = actor.ID, actor.Email
+	}
+
+	if event.TraceID == "" {
+		event.TraceID = traceID(ctx)
+	}
+
+	var errs []error
+	for _, sink := range a.sinks {
+		if err := sink.Write(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Actor is the authenticated user making a request.
+type Actor struct {
+	ID    int
+	Email string
+}
+
+type (
+	originKey struct{}
+	actorKey  struct{}
+)
+
+// WithOrigin attaches the client of the request to ctx for the events
+// recorded while serving it.
+func WithOrigin(ctx context.Context, origin entity.Origin) context.Context {
+	return context.WithValue(ctx, originKey{}, origin)
+}
+
+// WithActor attaches the authenticated user of the request to ctx.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// traceID returns the id of the jaeger trace ctx is part of.
+func traceID(ctx context.Context) string {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return ""
+	}
+
+	spanContext, ok := span.Context().(jaeger.SpanContext)
+	if !ok || !spanContext.IsValid() {
+		return ""
+	}
+
+	return spanContext.TraceID().String()
+}
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"io"
+	"os"
+	"sync"
+)
+
+// Datastore appends events to the audit table of the datastore.
+type Datastore struct {
+	store Store
+}
+
+func NewDatastore(store Store) *Datastore {
+	return &Datastore{store: store}
+}
+
+func (d *Datastore) Write(ctx context.Context, event *entity.AuditEvent) error {
+	return d.store.CreateAuditEvent(ctx, event)
+}
+
+// Writer writes events as JSON lines, one event per line.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// NewFile appends events to the JSON lines file at path, creating it when
+// missing.
+func NewFile(path string) (*Writer, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWriter(file), nil
+}
+
+func (w *Writer) Write(_ context.Context, event *entity.AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err = w.w.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+const authorizationCodePrefix = "oauth_code:"
+
+type AuthorizationCode interface {
+	Save(ctx context.Context, hash string, code *entity.AuthorizationCode, ttl time.Duration) error
+	// Take returns the code and deletes it in one step, so a code can be
+	// exchanged only once. Unknown or expired codes return nil.
+	Take(ctx context.Context, hash string) (*entity.AuthorizationCode, error)
+}
+
+type AuthorizationCodeCache struct {
+	redisCli *redis.Client
+}
+
+func NewAuthorizationCodeCache(redisCli *redis.Client) AuthorizationCode {
+	return &AuthorizationCodeCache{redisCli: redisCli}
+}
+
+func (c *AuthorizationCodeCache) Save(ctx context.Context, hash string, code *entity.AuthorizationCode, ttl time.Duration) error {
+	codeJson, err := json.Marshal(code)
+	if err != nil {
+		return err
+	}
+
+	return c.redisCli.Set(ctx, authorizationCodePrefix+hash, string(codeJson), ttl).Err()
+}
+
+func (c *AuthorizationCodeCache) Take(ctx context.Context, hash string) (*entity.AuthorizationCode, error) {
+	value, err := c.redisCli.GetDel(ctx, authorizationCodePrefix+hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var code *entity.AuthorizationCode
+	err = json.Unmarshal([]byte(value), &code)
+	if err != nil {
+		return nil, err
+	}
+
+	return code, nil
+}
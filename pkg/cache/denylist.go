@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+const (
+	deniedTokenPrefix   = "denylist:jti:"
+	deniedSessionPrefix = "denylist:sid:"
+	deniedUserPrefix    = "denylist:user:"
+)
+
+// Denylist keeps revoked access tokens until they would have expired anyway.
+type Denylist interface {
+	// RevokeToken denies a single access token by its jti.
+	RevokeToken(ctx context.Context, jti string, ttl time.Duration) error
+	// RevokeSession denies every access token issued for one login session.
+	RevokeSession(ctx context.Context, sid string, ttl time.Duration) error
+	// RevokeUser denies every access token of the user issued before the
+	// current second, tokens from this second are left to session revocation.
+	RevokeUser(ctx context.Context, userID int, ttl time.Duration) error
+	IsRevoked(ctx context.Context, jti, sid string, userID int, issuedAt time.Time) (bool, error)
+}
+
+type DenylistCache struct {
+	redisCli *redis.Client
+}
+
+func NewDenylistCache(redisCli *redis.Client) Denylist {
+	return &DenylistCache{redisCli: redisCli}
+}
+
+func (c *DenylistCache) RevokeToken(ctx context.Context, jti string, ttl time.Duration) error {
+	return c.deny(ctx, deniedTokenPrefix+jti, 1, ttl)
+}
+
+func (c *DenylistCache) RevokeSession(ctx context.Context, sid string, ttl time.Duration) error {
+	return c.deny(ctx, deniedSessionPrefix+sid, 1, ttl)
+}
+
+func (c *DenylistCache) RevokeUser(ctx context.Context, userID int, ttl time.Duration) error {
+	return c.deny(ctx, deniedUserPrefix+strconv.Itoa(userID), time.Now().Unix(), ttl)
+}
+
+func (c *DenylistCache) IsRevoked(ctx context.Context, jti, sid string, userID int, issuedAt time.Time) (bool, error) {
+	values, err := c.redisCli.MGet(ctx,
+		deniedTokenPrefix+jti,
+		deniedSessionPrefix+sid,
+		deniedUserPrefix+strconv.Itoa(userID),
+	).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if values[0] != nil || values[1] != nil {
+		return true, nil
+	}
+
+	if values[2] != nil {
+		revokedAt, err := strconv.ParseInt(values[2].(string), 10, 64)
+		if err != nil {
+			return false, err
+		}
+		return issuedAt.Unix() < revokedAt, nil
+	}
+
+	return false, nil
+}
+
+func (c *DenylistCache) deny(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return c.redisCli.Set(ctx, key, value, ttl).Err()
+}
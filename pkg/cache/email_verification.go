@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+const emailVerificationUsedPrefix = "email_verification_used:"
+
+type EmailVerification interface {
+	// MarkUsed reports false when the verification token with this id was
+	// already used, ttl should cover the rest of the token's lifetime.
+	MarkUsed(ctx context.Context, jti string, ttl time.Duration) (bool, error)
+}
+
+type EmailVerificationCache struct {
+	redisCli *redis.Client
+}
+
+func NewEmailVerificationCache(redisCli *redis.Client) EmailVerification {
+	return &EmailVerificationCache{redisCli: redisCli}
+}
+
+func (c *EmailVerificationCache) MarkUsed(ctx context.Context, jti string, ttl time.Duration) (bool, error) {
+	return c.redisCli.SetNX(ctx, emailVerificationUsedPrefix+jti, 1, ttl).Result()
+}
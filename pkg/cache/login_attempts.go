@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+const (
+	loginFailuresPrefix = "login_failures:"
+	loginBlockPrefix    = "login_block:"
+)
+
+// LoginAttempts counts failed logins per key, like an email or a client ip,
+// and blocks keys for a while.
+type LoginAttempts interface {
+	// RecordFailure counts a failure and returns the number of failures
+	// since the key was reset. The count is forgotten after window without
+	// further failures.
+	RecordFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+	// Block rejects logins for the key until ttl has passed, reason tells
+	// a backoff delay from a lockout.
+	Block(ctx context.Context, key, reason string, ttl time.Duration) error
+	// Blocked returns the reason and remaining time of a block, an empty
+	// reason when the key is not blocked.
+	Blocked(ctx context.Context, key string) (string, time.Duration, error)
+	// Reset forgets the failures and lifts the block.
+	Reset(ctx context.Context, key string) error
+}
+
+type LoginAttemptsCache struct {
+	redisCli *redis.Client
+}
+
+func NewLoginAttemptsCache(redisCli *redis.Client) LoginAttempts {
+	return &LoginAttemptsCache{redisCli: redisCli}
+}
+
+func (c *LoginAttemptsCache) RecordFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	pipe := c.redisCli.TxPipeline()
+	incr := pipe.Incr(ctx, loginFailuresPrefix+key)
+	pipe.Expire(ctx, loginFailuresPrefix+key, window)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
+func (c *LoginAttemptsCache) Block(ctx context.Context, key, reason string, ttl time.Duration) error {
+	return c.redisCli.Set(ctx, loginBlockPrefix+key, reason, ttl).Err()
+}
+
+func (c *LoginAttemptsCache) Blocked(ctx context.Context, key string) (string, time.Duration, error) {
+	pipe := c.redisCli.Pipeline()
+	get := pipe.Get(ctx, loginBlockPrefix+key)
+	pttl := pipe.PTTL(ctx, loginBlockPrefix+key)
+
+	_, err := pipe.Exec(ctx)
+	if errors.Is(err, redis.Nil) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	return get.Val(), pttl.Val(), nil
+}
+
+func (c *LoginAttemptsCache) Reset(ctx context.Context, key string) error {
+	return c.redisCli.Del(ctx, loginFailuresPrefix+key, loginBlockPrefix+key).Err()
+}
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+const (
+	mfaChallengePrefix = "mfa_challenge:"
+	mfaAttemptsPrefix  = "mfa_attempts:"
+	mfaUsedCodePrefix  = "mfa_used_code:"
+)
+
+// countAttemptScript increments the attempt counter KEYS[2] of the challenge
+// KEYS[1] and lets it expire together with the challenge. It returns 0 when
+// the challenge is gone.
+var countAttemptScript = redis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl == -2 then
+	return 0
+end
+
+local attempts = redis.call('INCR', KEYS[2])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+
+return attempts
+`)
+
+type MFA interface {
+	SaveChallenge(ctx context.Context, hash string, challenge *entity.MFAChallenge, ttl time.Duration) error
+	// GetChallenge returns nil when the challenge is unknown or expired.
+	GetChallenge(ctx context.Context, hash string) (*entity.MFAChallenge, error)
+	// CountAttempt atomically counts an attempt to answer the challenge and
+	// returns the attempts so far, 0 when the challenge is unknown or expired.
+	CountAttempt(ctx context.Context, hash string) (int64, error)
+	DeleteChallenge(ctx context.Context, hash string) error
+	// MarkCodeUsed reports false when the code was already accepted for the
+	// user, a TOTP code must not be replayed within its validity window.
+	MarkCodeUsed(ctx context.Context, userID int, code string, ttl time.Duration) (bool, error)
+}
+
+type MFACache struct {
+	redisCli *redis.Client
+}
+
+func NewMFACache(redisCli *redis.Client) MFA {
+	return &MFACache{redisCli: redisCli}
+}
+
+func (c *MFACache) SaveChallenge(ctx context.Context, hash string, challenge *entity.MFAChallenge, ttl time.Duration) error {
+	challengeJson, err := json.Marshal(challenge)
+	if err != nil {
+		return err
+	}
+
+	return c.redisCli.Set(ctx, mfaChallengePrefix+hash, string(challengeJson), ttl).Err()
+}
+
+func (c *MFACache) GetChallenge(ctx context.Context, hash string) (*entity.MFAChallenge, error) {
+	value, err := c.redisCli.Get(ctx, mfaChallengePrefix+hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var challenge *entity.MFAChallenge
+	err = json.Unmarshal([]byte(value), &challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func (c *MFACache) CountAttempt(ctx context.Context, hash string) (int64, error) {
+	return countAttemptScript.Run(ctx, c.redisCli, []string{mfaChallengePrefix + hash, mfaAttemptsPrefix + hash}).Int64()
+}
+
+func (c *MFACache) DeleteChallenge(ctx context.Context, hash string) error {
+	return c.redisCli.Del(ctx, mfaChallengePrefix+hash, mfaAttemptsPrefix+hash).Err()
+}
+
+func (c *MFACache) MarkCodeUsed(ctx context.Context, userID int, code string, ttl time.Duration) (bool, error) {
+	return c.redisCli.SetNX(ctx, mfaUsedCodePrefix+strconv.Itoa(userID)+":"+code, 1, ttl).Result()
+}
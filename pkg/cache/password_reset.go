@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+const (
+	passwordResetPrefix     = "password_reset:"
+	passwordResetUserPrefix = "password_reset_user:"
+)
+
+type PasswordReset interface {
+	Save(ctx context.Context, hash string, reset *entity.PasswordReset, ttl time.Duration) error
+	// Get returns the reset without using it up, nil when unknown or expired.
+	Get(ctx context.Context, hash string) (*entity.PasswordReset, error)
+	// Take returns the reset and deletes it, a token is used only once.
+	// Unknown or expired tokens return nil.
+	Take(ctx context.Context, hash string) (*entity.PasswordReset, error)
+	// RevokeUser deletes every outstanding reset of the user.
+	RevokeUser(ctx context.Context, userID int) error
+}
+
+type PasswordResetCache struct {
+	redisCli *redis.Client
+}
+
+func NewPasswordResetCache(redisCli *redis.Client) PasswordReset {
+	return &PasswordResetCache{redisCli: redisCli}
+}
+
+func (c *PasswordResetCache) Save(ctx context.Context, hash string, reset *entity.PasswordReset, ttl time.Duration) error {
+	resetJson, err := json.Marshal(reset)
+	if err != nil {
+		return err
+	}
+
+	userKey := passwordResetUserKey(reset.UserID)
+
+	pipe := c.redisCli.TxPipeline()
+	pipe.Set(ctx, passwordResetPrefix+hash, string(resetJson), ttl)
+	pipe.SAdd(ctx, userKey, hash)
+	pipe.Expire(ctx, userKey, ttl)
+	_, err = pipe.Exec(ctx)
+
+	return err
+}
+
+func (c *PasswordResetCache) Get(ctx context.Context, hash string) (*entity.PasswordReset, error) {
+	value, err := c.redisCli.Get(ctx, passwordResetPrefix+hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reset *entity.PasswordReset
+	err = json.Unmarshal([]byte(value), &reset)
+	if err != nil {
+		return nil, err
+	}
+
+	return reset, nil
+}
+
+func (c *PasswordResetCache) Take(ctx context.Context, hash string) (*entity.PasswordReset, error) {
+	value, err := c.redisCli.GetDel(ctx, passwordResetPrefix+hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reset *entity.PasswordReset
+	err = json.Unmarshal([]byte(value), &reset)
+	if err != nil {
+		return nil, err
+	}
+
+	return reset, nil
+}
+
+func (c *PasswordResetCache) RevokeUser(ctx context.Context, userID int) error {
+	userKey := passwordResetUserKey(userID)
+
+	hashes, err := c.redisCli.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(hashes)+1)
+	for _, hash := range hashes {
+		keys = append(keys, passwordResetPrefix+hash)
+	}
+	keys = append(keys, userKey)
+
+	return c.redisCli.Del(ctx, keys...).Err()
+}
+
+func passwordResetUserKey(userID int) string {
+	return passwordResetUserPrefix + strconv.Itoa(userID)
+}
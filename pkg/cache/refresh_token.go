@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+const (
+	refreshTokenPrefix  = "refresh_token:"
+	refreshUsedPrefix   = "refresh_token_used:"
+	refreshFamilyPrefix = "refresh_family:"
+	refreshUserPrefix   = "refresh_user:"
+)
+
+type RefreshToken interface {
+	// Save stores the token record under the token hash until it expires.
+	Save(ctx context.Context, hash string, token *entity.RefreshToken) error
+	// Get returns nil when the token is unknown, expired or revoked.
+	Get(ctx context.Context, hash string) (*entity.RefreshToken, error)
+	// MarkUsed reports false when the token has already been used.
+	MarkUsed(ctx context.Context, hash string, ttl time.Duration) (bool, error)
+	// IsUsed reports whether the token has already been rotated.
+	IsUsed(ctx context.Context, hash string) (bool, error)
+	// RevokeFamily deletes every token rotated from the same login.
+	RevokeFamily(ctx context.Context, familyID string) error
+	// RevokeUser deletes every token family of the user and returns their ids.
+	RevokeUser(ctx context.Context, userID int) ([]string, error)
+}
+
+type RefreshTokenCache struct {
+	redisCli *redis.Client
+}
+
+func NewRefreshTokenCache(redisCli *redis.Client) RefreshToken {
+	return &RefreshTokenCache{redisCli: redisCli}
+}
+
+func (c *RefreshTokenCache) Save(ctx context.Context, hash string, token *entity.RefreshToken) error {
+	tokenJson, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(token.ExpiresAt)
+	familyKey := refreshFamilyPrefix + token.FamilyID
+	userKey := refreshUserKey(token.UserID)
+
+	pipe := c.redisCli.TxPipeline()
+	pipe.Set(ctx, refreshTokenPrefix+hash, string(tokenJson), ttl)
+	pipe.SAdd(ctx, familyKey, hash)
+	pipe.Expire(ctx, familyKey, ttl)
+	pipe.SAdd(ctx, userKey, token.FamilyID)
+	pipe.Expire(ctx, userKey, ttl)
+	_, err = pipe.Exec(ctx)
+
+	return err
+}
+
+func (c *RefreshTokenCache) Get(ctx context.Context, hash string) (*entity.RefreshToken, error) {
+	value, err := c.redisCli.Get(ctx, refreshTokenPrefix+hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var token *entity.RefreshToken
+	err = json.Unmarshal([]byte(value), &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (c *RefreshTokenCache) MarkUsed(ctx context.Context, hash string, ttl time.Duration) (bool, error) {
+	return c.redisCli.SetNX(ctx, refreshUsedPrefix+hash, 1, ttl).Result()
+}
+
+func (c *RefreshTokenCache) IsUsed(ctx context.Context, hash string) (bool, error) {
+	n, err := c.redisCli.Exists(ctx, refreshUsedPrefix+hash).Result()
+	return n > 0, err
+}
+
+func (c *RefreshTokenCache) RevokeFamily(ctx context.Context, familyID string) error {
+	familyKey := refreshFamilyPrefix + familyID
+
+	hashes, err := c.redisCli.SMembers(ctx, familyKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(hashes)+1)
+	for _, hash := range hashes {
+		keys = append(keys, refreshTokenPrefix+hash)
+	}
+	keys = append(keys, familyKey)
+
+	return c.redisCli.Del(ctx, keys...).Err()
+}
+
+func (c *RefreshTokenCache) RevokeUser(ctx context.Context, userID int) ([]string, error) {
+	userKey := refreshUserKey(userID)
+
+	families, err := c.redisCli.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, familyID := range families {
+		if err = c.RevokeFamily(ctx, familyID); err != nil {
+			return nil, err
+		}
+	}
+
+	return families, c.redisCli.Del(ctx, userKey).Err()
+}
+
+func refreshUserKey(userID int) string {
+	return refreshUserPrefix + strconv.Itoa(userID)
+}
@@ -13,6 +13,7 @@ const UserCacheTimeout = 10 * time.Minute
 type User interface {
 	Get(ctx context.Context, key string) (*entity.User, error)
 	Set(ctx context.Context, key string, value *entity.User) error
+	Delete(ctx context.Context, keys ...string) error
 }
 
 type UserCache struct {
@@ -51,3 +52,11 @@ func (c *UserCache) Set(ctx context.Context, key string, value *entity.User) err
 
 	return c.redisCli.Set(ctx, key, string(userJson), c.Expiration).Err()
 }
+
+func (c *UserCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return c.redisCli.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+const webauthnSessionPrefix = "webauthn_session:"
+
+type WebAuthnSession interface {
+	Save(ctx context.Context, ceremonyID string, session *webauthn.SessionData, ttl time.Duration) error
+	// Take returns the ceremony session and deletes it, a challenge is
+	// answered only once. Unknown or expired ceremonies return nil.
+	Take(ctx context.Context, ceremonyID string) (*webauthn.SessionData, error)
+}
+
+type WebAuthnSessionCache struct {
+	redisCli *redis.Client
+}
+
+func NewWebAuthnSessionCache(redisCli *redis.Client) WebAuthnSession {
+	return &WebAuthnSessionCache{redisCli: redisCli}
+}
+
+func (c *WebAuthnSessionCache) Save(ctx context.Context, ceremonyID string, session *webauthn.SessionData, ttl time.Duration) error {
+	sessionJson, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return c.redisCli.Set(ctx, webauthnSessionPrefix+ceremonyID, string(sessionJson), ttl).Err()
+}
+
+func (c *WebAuthnSessionCache) Take(ctx context.Context, ceremonyID string) (*webauthn.SessionData, error) {
+	value, err := c.redisCli.GetDel(ctx, webauthnSessionPrefix+ceremonyID).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session *webauthn.SessionData
+	err = json.Unmarshal([]byte(value), &session)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
@@ -0,0 +1,83 @@
+// Package hasher hashes and verifies passwords. Hashes are self-describing
+// strings that carry the algorithm and its parameters, so hashes made with
+// older settings keep verifying after the configuration changes.
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"github.com/madyar997/sso-jcode/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+type PasswordHasher interface {
+	// Hash hashes the password with the configured algorithm and parameters.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hash. The hash may have been
+	// made with any supported algorithm.
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash reports whether hash was made with another algorithm or
+	// other parameters than Hash uses now.
+	NeedsRehash(hash string) bool
+}
+
+// algorithm is a single hashing scheme, recognizes tells its hashes apart.
+type algorithm interface {
+	PasswordHasher
+	recognizes(hash string) bool
+}
+
+// Hasher hashes with the configured algorithm and verifies hashes of all
+// supported ones.
+type Hasher struct {
+	current    algorithm
+	algorithms []algorithm
+}
+
+// New returns a hasher for cfg.Algorithm, bcrypt when it is empty. Zero
+// parameters fall back to the defaults of the algorithm.
+func New(cfg config.PasswordHash) (*Hasher, error) {
+	if cfg.BcryptCost != 0 && (cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost) {
+		return nil, fmt.Errorf("bcrypt cost %d out of range %d-%d", cfg.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	bcryptHasher := NewBcrypt(cfg.BcryptCost)
+	argon2idHasher := NewArgon2id(cfg.Argon2Memory, cfg.Argon2Iterations, cfg.Argon2Parallelism)
+
+	h := &Hasher{algorithms: []algorithm{bcryptHasher, argon2idHasher}}
+
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt, "":
+		h.current = bcryptHasher
+	case AlgorithmArgon2id:
+		h.current = argon2idHasher
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", cfg.Algorithm)
+	}
+
+	return h, nil
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *Hasher) Verify(hash, password string) (bool, error) {
+	for _, a := range h.algorithms {
+		if a.recognizes(hash) {
+			return a.Verify(hash, password)
+		}
+	}
+	return false, ErrUnknownHash
+}
+
+func (h *Hasher) NeedsRehash(hash string) bool {
+	return !h.current.recognizes(hash) || h.current.NeedsRehash(hash)
+}
@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"github.com/madyar997/sso-jcode/config"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTP delivers messages through an SMTP relay. Authentication is only used
+// when a username is configured, a local relay like MailHog needs none.
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTP(cfg config.Mail) *SMTP {
+	s := &SMTP{
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		from: cfg.From,
+	}
+	if cfg.Username != "" {
+		s.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return s
+}
+
+func (s *SMTP) Send(ctx context.Context, msg *Message) error {
+	from, err := mail.ParseAddress(s.from)
+	if err != nil {
+		return err
+	}
+
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	// net/smtp does not take a context, the send is not cancelled with it
+	return smtp.SendMail(s.addr, s.auth, from.Address, []string{to.Address}, format(s.from, msg, time.Now()))
+}
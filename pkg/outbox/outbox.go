@@ -0,0 +1,254 @@
+// Package outbox relays the domain events written to the outbox table to a
+// publisher. Delivery is at least once: an event is marked published only
+// after the publisher accepted it, so a crash in between publishes it again.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"github.com/madyar997/sso-jcode/config"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"go.uber.org/zap"
+	"os"
+	"time"
+)
+
+const (
+	PublisherWebhook = "webhook"
+	PublisherStdout  = "stdout"
+	PublisherNone    = "none"
+)
+
+const (
+	defaultPollInterval   = time.Second
+	defaultBatchSize      = 100
+	defaultLease          = time.Minute
+	defaultBackoff        = time.Second
+	defaultMaxBackoff     = 5 * time.Minute
+	defaultWebhookTimeout = 10 * time.Second
+	cleanupInterval       = time.Hour
+)
+
+// Message is what publishers send for an event. ID is stable across
+// redeliveries, consumers use it to skip duplicates.
+type Message struct {
+	ID          int                    `json:"id"`
+	Type        string                 `json:"type"`
+	AggregateID int                    `json:"aggregate_id"`
+	OccurredAt  time.Time              `json:"occurred_at"`
+	Data        map[string]interface{} `json:"data"`
+}
+
+func NewMessage(event *entity.OutboxEvent) *Message {
+	return &Message{
+		ID:          event.Id,
+		Type:        event.Type,
+		AggregateID: event.AggregateID,
+		OccurredAt:  event.CreatedAt,
+		Data:        event.Payload,
+	}
+}
+
+// Publisher delivers a message, an error means it has to be sent again.
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// Store is the datastore part the relay needs.
+type Store interface {
+	ClaimOutboxEvents(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*entity.OutboxEvent, error)
+	MarkOutboxEventPublished(ctx context.Context, id int, publishedAt time.Time) error
+	RetryOutboxEvent(ctx context.Context, id int, lastError string, nextAttemptAt time.Time) error
+	DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Relay polls the outbox table and publishes the due events. Failed events
+// are retried with exponential backoff until they get through.
+type Relay struct {
+	store     Store
+	publisher Publisher
+	logger    *logger.Logger
+
+	pollInterval time.Duration
+	batchSize    int
+	lease        time.Duration
+	backoff      Backoff
+	retention    time.Duration
+
+	now         func() time.Time
+	lastCleanup time.Time
+}
+
+func NewRelay(store Store, publisher Publisher, logger *logger.Logger, cfg config.Outbox) *Relay {
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	backoff := Backoff{
+		Base: Seconds(cfg.Backoff, defaultBackoff),
+		Max:  Seconds(cfg.MaxBackoff, defaultMaxBackoff),
+	}
+
+	return &Relay{
+		store:        store,
+		publisher:    publisher,
+		logger:       logger,
+		pollInterval: Seconds(cfg.PollInterval, defaultPollInterval),
+		batchSize:    batchSize,
+		lease:        Seconds(cfg.Lease, defaultLease),
+		backoff:      backoff,
+		retention:    time.Duration(cfg.Retention) * time.Second,
+		now:          time.Now,
+	}
+}
+
+// New builds the relay for the publisher named in cfg and the publishers
+// wired in code. There is no relay without a publisher, the events then stay
+// in the table.
+func New(cfg config.Outbox, store Store, logger *logger.Logger, publishers ...Publisher) (*Relay, error) {
+	switch cfg.Publisher {
+	case PublisherWebhook:
+		if cfg.WebhookURL == "" {
+			return nil, fmt.Errorf("outbox webhook publisher needs webhook_url")
+		}
+		publishers = append(publishers, NewWebhook(cfg.WebhookURL, Seconds(cfg.WebhookTimeout, defaultWebhookTimeout)))
+	case PublisherStdout:
+		publishers = append(publishers, NewWriter(os.Stdout))
+	case PublisherNone, "":
+	default:
+		return nil, fmt.Errorf("unknown outbox publisher %q", cfg.Publisher)
+	}
+
+	switch len(publishers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return NewRelay(store, publishers[0], logger, cfg), nil
+	default:
+		return NewRelay(store, Fanout(publishers), logger, cfg), nil
+	}
+}
+
+// Run publishes due events every poll interval until ctx is done. A nil
+// relay returns right away.
+func (r *Relay) Run(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.drain(ctx)
+		r.cleanup(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch claims one batch of due events and publishes it. It returns the
+// number of claimed events, published or not.
+func (r *Relay) Dispatch(ctx context.Context) (int, error) {
+	now := r.now().UTC()
+
+	events, err := r.store.ClaimOutboxEvents(ctx, now, now.Add(r.lease), r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if err = r.publisher.Publish(ctx, NewMessage(event)); err != nil {
+			r.retry(ctx, event, err)
+			continue
+		}
+
+		// the lease runs out and the event goes out again, which at least
+		// once delivery allows
+		if err = r.store.MarkOutboxEventPublished(ctx, event.Id, r.now().UTC()); err != nil {
+			r.logger.Error("could not mark outbox event published", zap.Int("event_id", event.Id), zap.Error(err))
+		}
+	}
+
+	return len(events), nil
+}
+
+// drain dispatches batches until one comes back short.
+func (r *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimed, err := r.Dispatch(ctx)
+		if err != nil {
+			r.logger.Error("could not claim outbox events", zap.Error(err))
+			return
+		}
+		if claimed < r.batchSize {
+			return
+		}
+	}
+}
+
+func (r *Relay) retry(ctx context.Context, event *entity.OutboxEvent, publishErr error) {
+	delay := r.backoff.Delay(event.Attempts)
+
+	r.logger.Warn("could not publish outbox event",
+		zap.Int("event_id", event.Id), zap.String("type", event.Type),
+		zap.Int("attempt", event.Attempts+1), zap.Duration("retry_in", delay), zap.Error(publishErr))
+
+	err := r.store.RetryOutboxEvent(ctx, event.Id, publishErr.Error(), r.now().UTC().Add(delay))
+	if err != nil {
+		r.logger.Error("could not schedule outbox event retry", zap.Int("event_id", event.Id), zap.Error(err))
+	}
+}
+
+// cleanup drops the events published longer than the retention ago, at most
+// once per cleanup interval.
+func (r *Relay) cleanup(ctx context.Context) {
+	now := r.now()
+	if r.retention <= 0 || now.Sub(r.lastCleanup) < cleanupInterval {
+		return
+	}
+	r.lastCleanup = now
+
+	deleted, err := r.store.DeletePublishedOutboxEvents(ctx, now.UTC().Add(-r.retention))
+	if err != nil {
+		r.logger.Error("could not delete published outbox events", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		r.logger.Info("deleted published outbox events", zap.Int64("count", deleted))
+	}
+}
+
+// Backoff is an exponential retry delay: Base after the first failure,
+// doubled with every further one up to Max.
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay is the wait after the given number of earlier failures.
+func (b Backoff) Delay(failures int) time.Duration {
+	delay := b.Base
+	for i := 0; i < failures && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+	return delay
+}
+
+// Seconds turns a setting in seconds into a duration, zero or less picks
+// the fallback.
+func Seconds(value int64, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+	return time.Duration(value) * time.Second
+}
@@ -0,0 +1,210 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"github.com/madyar997/sso-jcode/config"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"go.uber.org/zap"
+	"sort"
+	"testing"
+	"time"
+)
+
+// memoryStore is an outbox table in memory, claiming follows the datastore
+// drivers: due, unpublished events that are not leased to another relay.
+type memoryStore struct {
+	events map[int]*entity.OutboxEvent
+	leases map[int]time.Time
+}
+
+func newMemoryStore(events ...*entity.OutboxEvent) *memoryStore {
+	s := &memoryStore{events: map[int]*entity.OutboxEvent{}, leases: map[int]time.Time{}}
+	for _, event := range events {
+		s.events[event.Id] = event
+	}
+	return s
+}
+
+func (s *memoryStore) ClaimOutboxEvents(_ context.Context, now, lockedUntil time.Time,
+	limit int) ([]*entity.OutboxEvent, error) {
+	ids := make([]int, 0, len(s.events))
+	for id := range s.events {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	claimed := make([]*entity.OutboxEvent, 0)
+	for _, id := range ids {
+		event := s.events[id]
+		if event.PublishedAt != nil || event.NextAttemptAt.After(now) || s.leases[id].After(now) {
+			continue
+		}
+		if len(claimed) == limit {
+			break
+		}
+
+		s.leases[id] = lockedUntil
+		copied := *event
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) MarkOutboxEventPublished(_ context.Context, id int, publishedAt time.Time) error {
+	s.events[id].PublishedAt = &publishedAt
+	delete(s.leases, id)
+	return nil
+}
+
+func (s *memoryStore) RetryOutboxEvent(_ context.Context, id int, lastError string, nextAttemptAt time.Time) error {
+	event := s.events[id]
+	event.Attempts++
+	event.LastError = lastError
+	event.NextAttemptAt = nextAttemptAt
+	delete(s.leases, id)
+	return nil
+}
+
+func (s *memoryStore) DeletePublishedOutboxEvents(_ context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for id, event := range s.events {
+		if event.PublishedAt != nil && event.PublishedAt.Before(before) {
+			delete(s.events, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func newTestRelay(store Store, publisher Publisher, cfg config.Outbox, now *time.Time) *Relay {
+	relay := NewRelay(store, publisher, &logger.Logger{Logger: zap.NewNop()}, cfg)
+	relay.now = func() time.Time { return *now }
+	return relay
+}
+
+func TestDispatchPublishes(t *testing.T) {
+	now := time.Date(2023, 11, 20, 12, 0, 0, 0, time.UTC)
+	store := newMemoryStore(
+		&entity.OutboxEvent{Id: 1, Type: entity.EventUserRegistered, AggregateID: 7, CreatedAt: now},
+		&entity.OutboxEvent{Id: 2, Type: entity.EventUserDeleted, AggregateID: 7, CreatedAt: now},
+		&entity.OutboxEvent{Id: 3, Type: entity.EventUserCreated, AggregateID: 8, NextAttemptAt: now.Add(time.Minute)},
+	)
+	publisher := NewMemory()
+	relay := newTestRelay(store, publisher, config.Outbox{}, &now)
+
+	claimed, err := relay.Dispatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed != 2 {
+		t.Fatalf("claimed %d events, want 2", claimed)
+	}
+
+	messages := publisher.Messages()
+	if len(messages) != 2 || messages[0].ID != 1 || messages[1].ID != 2 {
+		t.Fatalf("published %+v, want events 1 and 2 in order", messages)
+	}
+	if messages[0].Type != entity.EventUserRegistered || messages[0].AggregateID != 7 {
+		t.Errorf("message %+v does not describe event 1", messages[0])
+	}
+	for _, id := range []int{1, 2} {
+		if store.events[id].PublishedAt == nil {
+			t.Errorf("event %d not marked published", id)
+		}
+	}
+	if store.events[3].PublishedAt != nil {
+		t.Error("event 3 published before it was due")
+	}
+
+	claimed, err = relay.Dispatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed != 0 || len(publisher.Messages()) != 2 {
+		t.Errorf("published events went out again, claimed %d", claimed)
+	}
+}
+
+func TestDispatchRetries(t *testing.T) {
+	now := time.Date(2023, 11, 20, 12, 0, 0, 0, time.UTC)
+	store := newMemoryStore(&entity.OutboxEvent{Id: 1, Type: entity.EventUserRegistered, AggregateID: 7})
+	publisher := NewMemory()
+	relay := newTestRelay(store, publisher, config.Outbox{Backoff: 2, MaxBackoff: 60}, &now)
+
+	publisher.Fail(errors.New("broker down"))
+
+	if _, err := relay.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	event := store.events[1]
+	if event.PublishedAt != nil || event.Attempts != 1 || event.LastError != "broker down" {
+		t.Fatalf("failed publish recorded as %+v", event)
+	}
+	if want := now.Add(2 * time.Second); !event.NextAttemptAt.Equal(want) {
+		t.Fatalf("next attempt at %s, want %s", event.NextAttemptAt, want)
+	}
+
+	// not due yet
+	if claimed, _ := relay.Dispatch(context.Background()); claimed != 0 {
+		t.Fatalf("claimed %d events before the retry was due", claimed)
+	}
+
+	now = now.Add(2 * time.Second)
+	if _, err := relay.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if event.Attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", event.Attempts)
+	}
+	if want := now.Add(4 * time.Second); !event.NextAttemptAt.Equal(want) {
+		t.Fatalf("second retry at %s, want %s", event.NextAttemptAt, want)
+	}
+
+	publisher.Fail(nil)
+	now = now.Add(4 * time.Second)
+	if _, err := relay.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if event.PublishedAt == nil {
+		t.Fatal("event not published once the publisher recovered")
+	}
+	if messages := publisher.Messages(); len(messages) != 1 || messages[0].ID != 1 {
+		t.Fatalf("published %+v, want event 1 once", messages)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{Base: time.Second, Max: 10 * time.Second}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{3, 8 * time.Second},
+		{4, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := backoff.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRelayBackoffDefaults(t *testing.T) {
+	relay := NewRelay(newMemoryStore(), NewMemory(), &logger.Logger{Logger: zap.NewNop()}, config.Outbox{})
+
+	if got := relay.backoff.Delay(0); got != defaultBackoff {
+		t.Errorf("first retry after %s, want %s", got, defaultBackoff)
+	}
+	if got := relay.backoff.Delay(64); got != defaultMaxBackoff {
+		t.Errorf("retry after many failures after %s, want %s", got, defaultMaxBackoff)
+	}
+}
@@ -0,0 +1,162 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Webhook posts every message as JSON to one url, any 2xx answer counts as
+// delivered.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (w *Webhook) Publish(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Id", strconv.Itoa(msg.ID))
+	req.Header.Set("X-Event-Type", msg.Type)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// drained so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Broker is the publishing side of a message broker client, NATS and Kafka
+// clients fit it with a thin adapter. Key is the aggregate id, usable as the
+// Kafka record key so that the events of one user stay in order; headers
+// carry the event id for deduplication, e.g. as Nats-Msg-Id.
+type Broker interface {
+	Publish(ctx context.Context, subject, key string, data []byte, headers map[string]string) error
+}
+
+// BrokerPublisher sends each message to the subject made of the prefix and
+// the event type, e.g. sso.user.registered.
+type BrokerPublisher struct {
+	broker Broker
+	prefix string
+}
+
+func NewBroker(broker Broker, subjectPrefix string) *BrokerPublisher {
+	return &BrokerPublisher{broker: broker, prefix: subjectPrefix}
+}
+
+func (b *BrokerPublisher) Publish(ctx context.Context, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return b.broker.Publish(ctx, b.prefix+msg.Type, strconv.Itoa(msg.AggregateID), data, map[string]string{
+		"Event-Id":   strconv.Itoa(msg.ID),
+		"Event-Type": msg.Type,
+	})
+}
+
+// Fanout publishes every message to each of the publishers. When one of them
+// fails the message goes out again to all, they have to skip duplicates
+// anyway.
+type Fanout []Publisher
+
+func (f Fanout) Publish(ctx context.Context, msg *Message) error {
+	var errs []error
+	for _, publisher := range f {
+		if err := publisher.Publish(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Writer writes messages as JSON lines, one message per line.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) Publish(_ context.Context, msg *Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err = w.w.Write(append(line, '\n'))
+	return err
+}
+
+// Memory keeps the published messages, for tests.
+type Memory struct {
+	mu       sync.Mutex
+	messages []*Message
+	err      error
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Publish(_ context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns the messages published so far, oldest first.
+func (m *Memory) Messages() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*Message(nil), m.messages...)
+}
+
+// Fail makes every following publish return err, nil lets them through again.
+func (m *Memory) Fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
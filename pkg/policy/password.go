@@ -0,0 +1,113 @@
+// Package policy checks user supplied secrets against configurable rules.
+package policy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/madyar997/sso-jcode/config"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMinLength applies when no minimum is configured, an empty password
+// is never accepted.
+const DefaultMinLength = 8
+
+// Password validates new passwords. The block list holds common and breached
+// passwords, compared case-insensitively.
+type Password struct {
+	cfg       config.PasswordPolicy
+	blocklist map[string]struct{}
+}
+
+// NewPassword loads the block list from cfg.BlocklistPath, one password per
+// line. Empty lines and lines starting with # are skipped.
+func NewPassword(cfg config.PasswordPolicy) (*Password, error) {
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = DefaultMinLength
+	}
+
+	p := &Password{cfg: cfg, blocklist: map[string]struct{}{}}
+
+	if cfg.BlocklistPath == "" {
+		return p, nil
+	}
+
+	file, err := os.Open(cfg.BlocklistPath)
+	if err != nil {
+		return nil, fmt.Errorf("password block list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.blocklist[strings.ToLower(line)] = struct{}{}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("password block list: %w", err)
+	}
+
+	return p, nil
+}
+
+// History is the number of previous passwords that may not be used again.
+func (p *Password) History() int {
+	return p.cfg.History
+}
+
+// Validate returns an error listing every rule the password breaks.
+func (p *Password) Validate(password string) error {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("at least %d characters", p.cfg.MinLength))
+	}
+	if p.cfg.MaxLength > 0 && length > p.cfg.MaxLength {
+		violations = append(violations, fmt.Sprintf("at most %d characters", p.cfg.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+
+	if p.cfg.RequireUpper && !upper {
+		violations = append(violations, "an upper case letter")
+	}
+	if p.cfg.RequireLower && !lower {
+		violations = append(violations, "a lower case letter")
+	}
+	if p.cfg.RequireDigit && !digit {
+		violations = append(violations, "a digit")
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		violations = append(violations, "a symbol")
+	}
+
+	if len(violations) > 0 {
+		return errors.New("password needs " + strings.Join(violations, ", "))
+	}
+
+	if _, ok := p.blocklist[strings.ToLower(password)]; ok {
+		return errors.New("password is too common")
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle keys are dropped from memory.
+const sweepInterval = time.Minute
+
+// Memory keeps the limiter state in the process. Every instance of the
+// service limits on its own, use Redis when running several.
+type Memory struct {
+	algorithm string
+
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryEntry struct {
+	// token bucket
+	tokens float64
+	last   time.Time
+	// sliding window
+	window   int64
+	current  int
+	previous int
+
+	expires time.Time
+}
+
+func NewMemory(algorithm string) *Memory {
+	return &Memory{
+		algorithm: algorithm,
+		entries:   make(map[string]*memoryEntry),
+		now:       time.Now,
+	}
+}
+
+func (m *Memory) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &memoryEntry{tokens: float64(limit.burst()), last: now}
+		m.entries[key] = entry
+	}
+
+	var result *Result
+
+	if m.algorithm == AlgorithmSlidingWindow {
+		window := now.UnixNano() / int64(limit.Period)
+		switch window - entry.window {
+		case 0:
+		case 1:
+			entry.previous, entry.current = entry.current, 0
+		default:
+			entry.previous, entry.current = 0, 0
+		}
+		entry.window = window
+
+		result = slidingWindow(entry.previous, entry.current, time.Duration(now.UnixNano()%int64(limit.Period)), limit)
+		if result.Allowed {
+			entry.current++
+		}
+		entry.expires = now.Add(2 * limit.Period)
+	} else {
+		entry.tokens, result = tokenBucket(entry.tokens, now.Sub(entry.last), limit)
+		entry.last = now
+		entry.expires = now.Add(time.Duration(float64(limit.Period) * float64(limit.burst()) / float64(limit.Requests)))
+	}
+
+	return result, nil
+}
+
+// sweep drops keys whose state went back to the initial one.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, entry := range m.entries {
+		if now.After(entry.expires) {
+			delete(m.entries, key)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Package ratelimit limits request rates per key with a token bucket or a
+// sliding window, kept in memory or in Redis.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/madyar997/sso-jcode/config"
+	"github.com/redis/go-redis/v9"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+
+	KeyIP     = "ip"
+	KeyClient = "client"
+)
+
+// Limit allows Requests per Period. Burst is the bucket size of the token
+// bucket, Requests when zero; the sliding window ignores it.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+type Result struct {
+	Allowed bool
+	// Limit and Remaining are in requests, Limit is the burst of the token
+	// bucket or the requests per window.
+	Limit     int
+	Remaining int
+	// RetryAfter is how long to wait before the next request is allowed,
+	// only set when the request was rejected.
+	RetryAfter time.Duration
+}
+
+type Limiter interface {
+	// Allow takes one request for key from limit.
+	Allow(ctx context.Context, key string, limit Limit) (*Result, error)
+}
+
+// NewLimiter returns the limiter selected by cfg. The redis client is only
+// used by the redis backend.
+func NewLimiter(cfg config.RateLimit, redisCli *redis.Client) (Limiter, error) {
+	switch cfg.Algorithm {
+	case AlgorithmTokenBucket, AlgorithmSlidingWindow:
+	case "":
+		cfg.Algorithm = AlgorithmTokenBucket
+	default:
+		return nil, fmt.Errorf("unknown rate limit algorithm %q", cfg.Algorithm)
+	}
+
+	switch cfg.Backend {
+	case BackendMemory, "":
+		return NewMemory(cfg.Algorithm), nil
+	case BackendRedis:
+		return NewRedis(redisCli, cfg.Algorithm), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", cfg.Backend)
+	}
+}
+
+// rule is a configured limit for a route, see config.RateLimitRule.
+type rule struct {
+	route  string
+	method string
+	path   string
+	key    string
+	limit  Limit
+}
+
+// Policy applies the configured rules to requests.
+type Policy struct {
+	limiter Limiter
+	rules   []rule
+}
+
+// New builds the policy from cfg. It returns nil when rate limiting is
+// disabled; a nil policy allows everything.
+func New(cfg config.RateLimit, redisCli *redis.Client) (*Policy, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	limiter, err := NewLimiter(cfg, redisCli)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]rule, 0, len(cfg.Rules))
+	for _, c := range cfg.Rules {
+		if c.Route == "" || c.Requests <= 0 || c.Period <= 0 {
+			return nil, fmt.Errorf("rate limit rule %q needs a route, requests and a period", c.Route)
+		}
+
+		switch c.Key {
+		case KeyIP, KeyClient:
+		case "":
+			c.Key = KeyIP
+		default:
+			return nil, fmt.Errorf("rate limit rule %q: unknown key %q", c.Route, c.Key)
+		}
+
+		r := rule{
+			route: c.Route,
+			path:  c.Route,
+			key:   c.Key,
+			limit: Limit{Requests: c.Requests, Period: time.Duration(c.Period) * time.Second, Burst: c.Burst},
+		}
+		if method, path, ok := strings.Cut(c.Route, " "); ok {
+			r.method, r.path = strings.ToUpper(method), strings.TrimSpace(path)
+		}
+
+		rules = append(rules, r)
+	}
+
+	return &Policy{limiter: limiter, rules: rules}, nil
+}
+
+// Check takes one request from every rule matching method and path. gRPC
+// calls pass an empty method and the full method name as the path. client
+// is only called when a rule is keyed by client and returns the ClientKey of
+// the caller, or "" for anonymous callers, who are limited by ip instead.
+//
+// It returns nil when no rule matches, otherwise the most restrictive
+// result: the longest wait of the rejecting rules or the fewest requests
+// remaining.
+func (p *Policy) Check(ctx context.Context, method, path, ip string, client func() string) (*Result, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	var (
+		result     *Result
+		clientKey  string
+		clientDone bool
+	)
+
+	for _, rule := range p.rules {
+		if rule.path != "*" && (rule.path != path || rule.method != "" && rule.method != method) {
+			continue
+		}
+
+		key := KeyIP + ":" + ip
+		if rule.key == KeyClient {
+			if !clientDone {
+				clientKey, clientDone = client(), true
+			}
+			if clientKey != "" {
+				key = clientKey
+			}
+		}
+
+		r, err := p.limiter.Allow(ctx, rule.route+"|"+key, rule.limit)
+		if err != nil {
+			return nil, err
+		}
+
+		if result == nil || moreRestrictive(r, result) {
+			result = r
+		}
+	}
+
+	return result, nil
+}
+
+// ClientKey identifies the caller of an access token for rules keyed by
+// client: the OAuth client the token was issued to, otherwise its user.
+func ClientKey(claims jwt.MapClaims) string {
+	if clientID, _ := claims["client_id"].(string); clientID != "" {
+		return "client:" + clientID
+	}
+	if sub, _ := claims["sub"].(string); sub != "" {
+		return "user:" + sub
+	}
+	return ""
+}
+
+func moreRestrictive(a, b *Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+// tokenBucket refills limit.Requests tokens per period up to the burst and
+// takes one. tokens and the result are in units of whole requests.
+func tokenBucket(tokens float64, elapsed time.Duration, limit Limit) (float64, *Result) {
+	burst := float64(limit.burst())
+	rate := float64(limit.Requests) / float64(limit.Period)
+
+	tokens += float64(elapsed) * rate
+	if tokens > burst {
+		tokens = burst
+	}
+
+	if tokens >= 1 {
+		tokens--
+		return tokens, &Result{Allowed: true, Limit: limit.burst(), Remaining: int(tokens)}
+	}
+
+	return tokens, &Result{Limit: limit.burst(), RetryAfter: time.Duration((1 - tokens) / rate)}
+}
+
+// slidingWindow approximates a sliding window from the counts of the current
+// and the previous fixed window, the previous one weighted by how much of it
+// still overlaps the sliding window.
+func slidingWindow(previous, current int, elapsed time.Duration, limit Limit) *Result {
+	period := float64(limit.Period)
+	weight := (period - float64(elapsed)) / period
+	count := float64(previous)*weight + float64(current)
+
+	if count+1 <= float64(limit.Requests) {
+		return &Result{Allowed: true, Limit: limit.Requests, Remaining: int(float64(limit.Requests) - count - 1)}
+	}
+
+	// the current window alone is full, wait for the next one and for this
+	// window to weigh little enough as the previous one
+	if current+1 > limit.Requests {
+		wait := float64(limit.Period - elapsed)
+		if current > 0 {
+			wait += period * (1 - float64(limit.Requests-1)/float64(current))
+		}
+		return &Result{Limit: limit.Requests, RetryAfter: time.Duration(wait)}
+	}
+
+	// otherwise wait until enough of the previous window slid out
+	wait := (period - float64(elapsed)) - float64(limit.Requests-current-1)*period/float64(previous)
+
+	return &Result{Limit: limit.Requests, RetryAfter: time.Duration(wait)}
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+const redisKeyPrefix = "ratelimit:"
+
+// tokenBucketScript mirrors tokenBucket. Times are in microseconds passed by
+// the caller, the instances share the state and should have synced clocks.
+var tokenBucketScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local requests = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local burst = tonumber(ARGV[4])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+
+local rate = requests / period
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate / 1000) + 1000)
+
+return {allowed, math.floor(tokens), retry}
+`)
+
+// slidingWindowScript counts requests per fixed window in KEYS[1] (current)
+// and KEYS[2] (previous). The decision is made in Go by slidingWindow, the
+// script only increments when told the request fits.
+var slidingWindowScript = redis.NewScript(`
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local weight = tonumber(ARGV[1])
+local requests = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+
+if previous * weight + current + 1 <= requests then
+	current = redis.call('INCR', KEYS[1])
+	redis.call('PEXPIRE', KEYS[1], 2 * period)
+	return {1, previous, current - 1}
+end
+
+return {0, previous, current}
+`)
+
+// Redis keeps the limiter state in Redis, shared by every instance.
+type Redis struct {
+	redisCli  *redis.Client
+	algorithm string
+}
+
+func NewRedis(redisCli *redis.Client, algorithm string) *Redis {
+	return &Redis{redisCli: redisCli, algorithm: algorithm}
+}
+
+func (r *Redis) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	now := time.Now()
+
+	if r.algorithm == AlgorithmSlidingWindow {
+		return r.slidingWindow(ctx, key, limit, now)
+	}
+
+	values, err := tokenBucketScript.Run(ctx, r.redisCli, []string{redisKeyPrefix + key},
+		now.UnixMicro(), limit.Requests, limit.Period.Microseconds(), limit.burst()).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit.burst(),
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+	}, nil
+}
+
+func (r *Redis) slidingWindow(ctx context.Context, key string, limit Limit, now time.Time) (*Result, error) {
+	window := now.UnixNano() / int64(limit.Period)
+	elapsed := time.Duration(now.UnixNano() % int64(limit.Period))
+	weight := float64(limit.Period-elapsed) / float64(limit.Period)
+
+	keys := []string{
+		redisKeyPrefix + key + ":" + strconv.FormatInt(window, 10),
+		redisKeyPrefix + key + ":" + strconv.FormatInt(window-1, 10),
+	}
+
+	values, err := slidingWindowScript.Run(ctx, r.redisCli, keys,
+		strconv.FormatFloat(weight, 'f', -1, 64), limit.Requests, limit.Period.Milliseconds()).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	// recompute the numbers from the counts the decision was made on
+	return slidingWindow(int(values[1]), int(values[2]), elapsed, limit), nil
+}
@@ -0,0 +1,198 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"sync"
+	"time"
+)
+
+const rsaKeyBits = 2048
+
+var ErrUnknownKeyID = errors.New("signer: unknown or expired key id")
+
+// KeyRing signs with the active key and keeps verifying tokens signed by
+// retired keys until their grace period runs out.
+type KeyRing struct {
+	mu     sync.RWMutex
+	active *Key
+	keys   map[string]*Key
+	grace  time.Duration
+	dir    string
+}
+
+// NewKeyRing starts a ring from the configured key. When dir is set, keys
+// created by earlier rotations are restored from it and new ones are saved there.
+func NewKeyRing(initial *Key, grace time.Duration, dir string) (*KeyRing, error) {
+	kr := &KeyRing{
+		active: initial,
+		keys:   map[string]*Key{initial.ID: initial},
+		grace:  grace,
+		dir:    dir,
+	}
+
+	if dir == "" {
+		return kr, nil
+	}
+
+	rotated, err := loadKeys(dir, initial.Method.Alg())
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range rotated {
+		kr.active.RetiredAt = k.createdAt
+		kr.active = k.Key
+		kr.keys[k.ID] = k.Key
+	}
+
+	return kr, nil
+}
+
+func (kr *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	kr.mu.RLock()
+	key := kr.active
+	kr.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.private)
+}
+
+// Keyfunc picks the verification key by the kid header. Tokens issued before
+// key ids were introduced carry no kid and are checked against the active key.
+func (kr *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	key := kr.active
+	if kid, ok := token.Header["kid"].(string); ok {
+		key, ok = kr.keys[kid]
+		if !ok || kr.expired(key, time.Now()) {
+			return nil, ErrUnknownKeyID
+		}
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+func (kr *KeyRing) JWKS() JWKS {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	set := JWKS{Keys: []JWK{}}
+	now := time.Now()
+	for _, k := range kr.keys {
+		if k.asymmetric() && !kr.expired(k, now) {
+			set.Keys = append(set.Keys, NewJWK(k.ID, k.Method.Alg(), k.public))
+		}
+	}
+	return set
+}
+
+func (kr *KeyRing) Algorithm() string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	return kr.active.Method.Alg()
+}
+
+// ActiveKeyID returns the kid stamped on newly issued tokens.
+func (kr *KeyRing) ActiveKeyID() string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	return kr.active.ID
+}
+
+// Rotate generates a new key with the same algorithm, makes it active and
+// retires the previous one. Keys past their grace period are dropped.
+func (kr *KeyRing) Rotate() (*Key, error) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	key, err := generateKey(kr.active.Method.Alg())
+	if err != nil {
+		return nil, err
+	}
+
+	if kr.dir != "" {
+		if err = saveKey(kr.dir, key); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	kr.active.RetiredAt = now
+	kr.active = key
+	kr.keys[key.ID] = key
+
+	for id, k := range kr.keys {
+		if kr.expired(k, now) {
+			delete(kr.keys, id)
+			if kr.dir != "" {
+				removeKey(kr.dir, id)
+			}
+		}
+	}
+
+	return key, nil
+}
+
+func (kr *KeyRing) expired(k *Key, now time.Time) bool {
+	return !k.RetiredAt.IsZero() && now.After(k.RetiredAt.Add(kr.grace))
+}
+
+func generateKey(alg string) (*Key, error) {
+	id, err := newKeyID()
+	if err != nil {
+		return nil, err
+	}
+
+	switch alg {
+	case AlgHS256:
+		secret := make([]byte, 32)
+		if _, err = rand.Read(secret); err != nil {
+			return nil, err
+		}
+		return NewHMACKey(id, secret), nil
+	case AlgRS256:
+		private, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		return NewAsymmetricKey(id, alg, private)
+	case AlgES256:
+		private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return NewAsymmetricKey(id, alg, private)
+	case AlgEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return NewAsymmetricKey(id, alg, private)
+	default:
+		return nil, fmt.Errorf("signer: unsupported algorithm %q", alg)
+	}
+}
+
+func newKeyID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,159 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	keyFileExt   = ".pem"
+	hmacPEMBlock = "HMAC KEY"
+)
+
+var ErrInvalidPEM = errors.New("signer: no PEM block found")
+
+// ParsePrivateKey accepts PKCS#8, PKCS#1 (RSA) and SEC 1 (EC) encoded keys.
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+
+	return parseBlock(block)
+}
+
+func parseBlock(block *pem.Block) (crypto.Signer, error) {
+	var (
+		key interface{}
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signer: parsing %s: %w", block.Type, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("signer: unsupported key type %T", key)
+	}
+	return signer, nil
+}
+
+type storedKey struct {
+	*Key
+	createdAt time.Time
+}
+
+// loadKeys reads the keys saved by earlier rotations, oldest first. The file
+// modification time is the moment the key became active.
+func loadKeys(dir, alg string) ([]storedKey, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+keyFileExt))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]storedKey, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := readKey(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if key.Method.Alg() != alg {
+			log.Printf("[WARN] skipping %s key %s, configured algorithm is %s", key.Method.Alg(), key.ID, alg)
+			continue
+		}
+
+		keys = append(keys, storedKey{Key: key, createdAt: info.ModTime()})
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].createdAt.Before(keys[j].createdAt) })
+
+	return keys, nil
+}
+
+func readKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w in %s", ErrInvalidPEM, path)
+	}
+
+	id := strings.TrimSuffix(filepath.Base(path), keyFileExt)
+	if block.Type == hmacPEMBlock {
+		return NewHMACKey(id, block.Bytes), nil
+	}
+
+	private, err := parseBlock(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAsymmetricKey(id, algFor(private), private)
+}
+
+func saveKey(dir string, key *Key) error {
+	block := &pem.Block{Type: hmacPEMBlock}
+	if key.asymmetric() {
+		der, err := x509.MarshalPKCS8PrivateKey(key.private)
+		if err != nil {
+			return err
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	} else {
+		block.Bytes = key.private.([]byte)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, key.ID+keyFileExt), pem.EncodeToMemory(block), 0o600)
+}
+
+func removeKey(dir, id string) {
+	if err := os.Remove(filepath.Join(dir, id+keyFileExt)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("[WARN] cannot remove expired signing key %s: %v", id, err)
+	}
+}
+
+func algFor(key crypto.Signer) string {
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		return AlgRS256
+	case *ecdsa.PrivateKey:
+		return AlgES256
+	case ed25519.PrivateKey:
+		return AlgEdDSA
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,133 @@
+// Package signer signs and verifies the JWTs issued by the service.
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/madyar997/sso-jcode/config"
+	"os"
+	"time"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+
+	defaultKeyID = "default"
+)
+
+type Signer interface {
+	// Sign returns the compact serialization of a token with the given claims.
+	Sign(claims jwt.Claims) (string, error)
+	// Keyfunc resolves the verification key for jwt.Parse.
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	// JWKS returns the public keys relying parties use to verify tokens.
+	JWKS() JWKS
+	// Algorithm is the JWS alg of newly signed tokens.
+	Algorithm() string
+}
+
+// New builds the key ring described by the jwt config section. An empty
+// algorithm keeps the legacy HS256 behaviour with the shared secret key.
+func New(cfg config.Jwt) (*KeyRing, error) {
+	var (
+		key *Key
+		err error
+	)
+	switch cfg.Algorithm {
+	case "", AlgHS256:
+		if cfg.SecretKey == "" {
+			return nil, fmt.Errorf("signer: secret_key is required for %s", AlgHS256)
+		}
+		key = NewHMACKey(cfg.KeyID, []byte(cfg.SecretKey))
+	case AlgRS256, AlgES256, AlgEdDSA:
+		var private crypto.Signer
+		private, err = LoadPrivateKey(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		key, err = NewAsymmetricKey(cfg.KeyID, cfg.Algorithm, private)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("signer: unsupported algorithm %q", cfg.Algorithm)
+	}
+
+	grace := time.Duration(cfg.RotationGracePeriod) * time.Second
+	if grace <= 0 {
+		grace = time.Duration(cfg.RefreshTokenTTL) * time.Second
+	}
+
+	return NewKeyRing(key, grace, cfg.KeysDir)
+}
+
+// Key is a single signing key identified by its kid.
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	RetiredAt time.Time
+
+	private interface{}
+	public  interface{}
+}
+
+func NewHMACKey(id string, secret []byte) *Key {
+	if id == "" {
+		id = defaultKeyID
+	}
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, private: secret, public: secret}
+}
+
+// NewAsymmetricKey checks that the private key fits the algorithm. The kid
+// defaults to a thumbprint of the public key.
+func NewAsymmetricKey(id, alg string, private crypto.Signer) (*Key, error) {
+	if err := checkKey(alg, private); err != nil {
+		return nil, err
+	}
+	if id == "" {
+		id = Thumbprint(private.Public())
+	}
+	return &Key{ID: id, Method: jwt.GetSigningMethod(alg), private: private, public: private.Public()}, nil
+}
+
+func (k *Key) asymmetric() bool {
+	return k.Method.Alg() != AlgHS256
+}
+
+func checkKey(alg string, key crypto.Signer) error {
+	ok := false
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		ok = alg == AlgRS256
+	case *ecdsa.PrivateKey:
+		ok = alg == AlgES256 && k.Curve == elliptic.P256()
+	case ed25519.PrivateKey:
+		ok = alg == AlgEdDSA
+	}
+	if !ok {
+		return fmt.Errorf("signer: %T cannot be used with %s", key, alg)
+	}
+	return nil
+}
+
+// LoadPrivateKey reads a PEM encoded RSA, ECDSA or Ed25519 private key.
+func LoadPrivateKey(path string) (crypto.Signer, error) {
+	if path == "" {
+		return nil, fmt.Errorf("signer: private_key_path is not set")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("signer: reading private key: %w", err)
+	}
+
+	return ParsePrivateKey(data)
+}
@@ -0,0 +1,247 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/madyar997/sso-jcode/config"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"github.com/madyar997/sso-jcode/pkg/outbox"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultTimeout      = 10 * time.Second
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+	defaultLease        = time.Minute
+	defaultMaxAttempts  = 8
+	defaultBackoff      = 10 * time.Second
+	defaultMaxBackoff   = time.Hour
+
+	// responseBodyLimit is how much of an answer is kept on the delivery.
+	responseBodyLimit = 1024
+)
+
+var errEndpointDisabled = errors.New("endpoint is disabled")
+
+// Store is the datastore part the dispatcher needs.
+type Store interface {
+	GetWebhookEndpoints(ctx context.Context) ([]*entity.WebhookEndpoint, error)
+	GetWebhookEndpoint(ctx context.Context, id int) (*entity.WebhookEndpoint, error)
+	CreateWebhookDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*entity.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+}
+
+// Dispatcher turns outbox messages into deliveries for the subscribed
+// endpoints and sends them, retrying failures with exponential backoff.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	logger *logger.Logger
+
+	pollInterval time.Duration
+	batchSize    int
+	lease        time.Duration
+	maxAttempts  int
+	backoff      outbox.Backoff
+
+	now func() time.Time
+}
+
+func NewDispatcher(store Store, logger *logger.Logger, cfg config.Webhooks) *Dispatcher {
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	backoff := outbox.Backoff{
+		Base: outbox.Seconds(cfg.Backoff, defaultBackoff),
+		Max:  outbox.Seconds(cfg.MaxBackoff, defaultMaxBackoff),
+	}
+
+	return &Dispatcher{
+		store:        store,
+		client:       &http.Client{Timeout: outbox.Seconds(cfg.Timeout, defaultTimeout)},
+		logger:       logger,
+		pollInterval: outbox.Seconds(cfg.PollInterval, defaultPollInterval),
+		batchSize:    batchSize,
+		lease:        outbox.Seconds(cfg.Lease, defaultLease),
+		maxAttempts:  maxAttempts,
+		backoff:      backoff,
+		now:          time.Now,
+	}
+}
+
+// Publish queues a delivery of msg for every enabled endpoint subscribed to
+// its type. It is the outbox publisher of the webhooks, a message published
+// twice is queued once per endpoint.
+func (d *Dispatcher) Publish(ctx context.Context, msg *outbox.Message) error {
+	endpoints, err := d.store.GetWebhookEndpoints(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	now := d.now().UTC()
+
+	deliveries := make([]*entity.WebhookDelivery, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if !endpoint.Enabled || !endpoint.Subscribes(msg.Type) {
+			continue
+		}
+
+		deliveries = append(deliveries, &entity.WebhookDelivery{
+			EndpointID:    endpoint.Id,
+			EventID:       msg.ID,
+			EventType:     msg.Type,
+			Payload:       string(payload),
+			Status:        entity.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+
+	return d.store.CreateWebhookDeliveries(ctx, deliveries)
+}
+
+// Run sends due deliveries every poll interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			claimed, err := d.Dispatch(ctx)
+			if err != nil {
+				d.logger.Error("could not claim webhook deliveries", zap.Error(err))
+				break
+			}
+			if claimed < d.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch claims one batch of due deliveries and sends it. It returns the
+// number of claimed deliveries, sent or not.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	now := d.now().UTC()
+
+	deliveries, err := d.store.ClaimWebhookDeliveries(ctx, now, now.Add(d.lease), d.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		if err = d.Deliver(ctx, delivery); err != nil {
+			d.logger.Error("could not record webhook delivery", zap.Int("delivery_id", delivery.Id), zap.Error(err))
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// Deliver makes one attempt of the delivery and stores its outcome: success,
+// a retry after the backoff, or failure once the attempts are used up. The
+// returned error is about storing it, a failed attempt is not one.
+func (d *Dispatcher) Deliver(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	delivery.Attempts++
+
+	var attemptErr error
+	retry := true
+
+	endpoint, err := d.store.GetWebhookEndpoint(ctx, delivery.EndpointID)
+	switch {
+	case err != nil:
+		attemptErr = err
+	case !endpoint.Enabled:
+		// retrying is pointless until it is enabled again
+		attemptErr, retry = errEndpointDisabled, false
+	default:
+		attemptErr = d.send(ctx, endpoint, delivery)
+	}
+
+	now := d.now().UTC()
+
+	switch {
+	case attemptErr == nil:
+		delivery.Status = entity.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case !retry || delivery.Attempts >= d.maxAttempts:
+		delivery.Status = entity.DeliveryFailed
+		delivery.LastError = attemptErr.Error()
+	default:
+		delivery.Status = entity.DeliveryPending
+		delivery.LastError = attemptErr.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff.Delay(delivery.Attempts - 1))
+	}
+
+	if attemptErr != nil {
+		d.logger.Warn("webhook delivery failed",
+			zap.Int("delivery_id", delivery.Id), zap.Int("endpoint_id", delivery.EndpointID),
+			zap.Int("attempt", delivery.Attempts), zap.String("status", delivery.Status), zap.Error(attemptErr))
+	}
+
+	return d.store.UpdateWebhookDelivery(ctx, delivery)
+}
+
+// send posts the payload with a fresh signature and keeps the answer on the
+// delivery. Any 2xx status counts as delivered.
+func (d *Dispatcher) send(ctx context.Context, endpoint *entity.WebhookEndpoint, delivery *entity.WebhookDelivery) error {
+	delivery.ResponseStatus, delivery.ResponseBody = 0, ""
+
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.Id))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, d.now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	answer, _ := io.ReadAll(io.LimitReader(resp.Body, responseBodyLimit))
+	// the rest is drained so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	delivery.ResponseStatus = resp.StatusCode
+	delivery.ResponseBody = string(answer)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return nil
+}
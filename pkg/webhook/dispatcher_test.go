@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/madyar997/sso-jcode/config"
+	"github.com/madyar997/sso-jcode/internal/entity"
+	"github.com/madyar997/sso-jcode/pkg/logger"
+	"github.com/madyar997/sso-jcode/pkg/outbox"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// endpointStore serves fixed endpoints and records what the dispatcher
+// writes. Claiming is left to the datastore drivers and not faked here.
+type endpointStore struct {
+	endpoints []*entity.WebhookEndpoint
+	created   []*entity.WebhookDelivery
+	updated   []entity.WebhookDelivery
+}
+
+func (s *endpointStore) GetWebhookEndpoints(context.Context) ([]*entity.WebhookEndpoint, error) {
+	return s.endpoints, nil
+}
+
+func (s *endpointStore) GetWebhookEndpoint(_ context.Context, id int) (*entity.WebhookEndpoint, error) {
+	for _, endpoint := range s.endpoints {
+		if endpoint.Id == id {
+			return endpoint, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *endpointStore) CreateWebhookDeliveries(_ context.Context, deliveries []*entity.WebhookDelivery) error {
+	s.created = append(s.created, deliveries...)
+	return nil
+}
+
+func (s *endpointStore) ClaimWebhookDeliveries(context.Context, time.Time, time.Time, int) ([]*entity.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (s *endpointStore) UpdateWebhookDelivery(_ context.Context, delivery *entity.WebhookDelivery) error {
+	s.updated = append(s.updated, *delivery)
+	return nil
+}
+
+func TestPublishQueuesSubscribedEndpoints(t *testing.T) {
+	store := &endpointStore{endpoints: []*entity.WebhookEndpoint{
+		{Id: 1, Enabled: true, EventTypes: []string{entity.EventUserRegistered}},
+		{Id: 2, Enabled: true, EventTypes: []string{entity.EventUserDeleted}},
+		{Id: 3, Enabled: true, EventTypes: []string{entity.WebhookAllEvents}},
+		{Id: 4, Enabled: false, EventTypes: []string{entity.WebhookAllEvents}},
+	}}
+	dispatcher := NewDispatcher(store, &logger.Logger{Logger: zap.NewNop()}, config.Webhooks{})
+
+	msg := &outbox.Message{ID: 42, Type: entity.EventUserRegistered, AggregateID: 7}
+	if err := dispatcher.Publish(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.created) != 2 || store.created[0].EndpointID != 1 || store.created[1].EndpointID != 3 {
+		t.Fatalf("queued %+v, want deliveries for endpoints 1 and 3", store.created)
+	}
+
+	payload, _ := json.Marshal(msg)
+	for _, delivery := range store.created {
+		if delivery.EventID != 42 || delivery.Status != entity.DeliveryPending || delivery.Payload != string(payload) {
+			t.Errorf("delivery %+v does not carry the message", delivery)
+		}
+	}
+}
+
+func TestDeliverSignsAndReplays(t *testing.T) {
+	status := http.StatusBadGateway
+	var bodies [][]byte
+	var signatures []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		signatures = append(signatures, r.Header.Get(HeaderSignature))
+		if r.Header.Get(HeaderEvent) != entity.EventUserRegistered || r.Header.Get(HeaderDelivery) != "5" {
+			t.Errorf("headers %v do not describe the delivery", r.Header)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	store := &endpointStore{endpoints: []*entity.WebhookEndpoint{
+		{Id: 1, URL: server.URL, Secret: "whsec_one", Enabled: true, EventTypes: []string{entity.WebhookAllEvents}},
+	}}
+	dispatcher := NewDispatcher(store, &logger.Logger{Logger: zap.NewNop()}, config.Webhooks{MaxAttempts: 1})
+
+	sentAt := time.Now().UTC().Truncate(time.Second)
+	dispatcher.now = func() time.Time { return sentAt }
+
+	delivery := &entity.WebhookDelivery{Id: 5, EndpointID: 1, EventType: entity.EventUserRegistered,
+		Payload: `{"id":42}`, Status: entity.DeliveryPending}
+
+	if err := dispatcher.Deliver(context.Background(), delivery); err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != entity.DeliveryFailed || delivery.ResponseStatus != http.StatusBadGateway {
+		t.Fatalf("non-2xx answer with no attempts left recorded as %+v", delivery)
+	}
+	if err := Verify("whsec_one", signatures[0], bodies[0], sentAt, DefaultTolerance); err != nil {
+		t.Fatalf("signature does not verify with the endpoint secret: %v", err)
+	}
+
+	// a replay sends the same bytes with a fresh signature
+	status = http.StatusNoContent
+	sentAt = sentAt.Add(time.Minute)
+
+	if err := dispatcher.Deliver(context.Background(), delivery); err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != entity.DeliverySucceeded || delivery.Attempts != 2 || delivery.DeliveredAt == nil {
+		t.Fatalf("replayed delivery is %+v", delivery)
+	}
+	if string(bodies[1]) != delivery.Payload || signatures[1] == signatures[0] {
+		t.Errorf("replay sent %s signed %s", bodies[1], signatures[1])
+	}
+	if err := Verify("whsec_one", signatures[1], bodies[1], sentAt, DefaultTolerance); err != nil {
+		t.Errorf("replay signature does not verify: %v", err)
+	}
+	if len(store.updated) != 2 {
+		t.Errorf("stored %d outcomes, want one per attempt", len(store.updated))
+	}
+}
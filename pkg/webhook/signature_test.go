@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":1,"type":"user.registered"}`)
+	sentAt := time.Date(2023, 11, 22, 9, 0, 0, 0, time.UTC)
+
+	header := Sign("whsec_test", sentAt, body)
+	if !strings.HasPrefix(header, "t=1700643600,v1=") {
+		t.Fatalf("header %q does not carry the timestamp first", header)
+	}
+
+	if err := Verify("whsec_test", header, body, sentAt.Add(time.Minute), DefaultTolerance); err != nil {
+		t.Fatalf("own signature rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+	}{
+		{"other secret", "whsec_other", header, body},
+		{"changed body", "whsec_test", header, []byte(`{"id":2,"type":"user.registered"}`)},
+		{"changed timestamp", "whsec_test", strings.Replace(header, "t=1700643600", "t=1700643601", 1), body},
+		{"missing signature", "whsec_test", "t=1700643600", body},
+		{"missing timestamp", "whsec_test", header[strings.Index(header, "v1="):], body},
+		{"garbage", "whsec_test", "not a signature", body},
+	}
+
+	for _, tt := range tests {
+		err := Verify(tt.secret, tt.header, tt.body, sentAt, DefaultTolerance)
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: got %v, want ErrInvalidSignature", tt.name, err)
+		}
+	}
+}
+
+func TestVerifyTolerance(t *testing.T) {
+	body := []byte(`{}`)
+	sentAt := time.Date(2023, 11, 22, 9, 0, 0, 0, time.UTC)
+	header := Sign("whsec_test", sentAt, body)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want error
+	}{
+		{"on time", sentAt, nil},
+		{"at the limit", sentAt.Add(DefaultTolerance), nil},
+		{"too old", sentAt.Add(DefaultTolerance + time.Second), ErrSignatureExpired},
+		{"clock behind", sentAt.Add(-DefaultTolerance), nil},
+		{"from the future", sentAt.Add(-DefaultTolerance - time.Second), ErrSignatureExpired},
+	}
+
+	for _, tt := range tests {
+		if err := Verify("whsec_test", header, body, tt.now, DefaultTolerance); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}